@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"tlgread/pkg/ninep"
+)
+
+// acmeFS opens files of acme's file system, either mounted (Plan 9)
+// or through the plan9port namespace socket.
+type acmeFS interface {
+	open(name string, mode uint8) (io.ReadWriteCloser, error)
+}
+
+type mountFS string
+
+func (m mountFS) open(name string, mode uint8) (io.ReadWriteCloser, error) {
+	flag := os.O_RDONLY
+	switch mode & 3 {
+	case ninep.OWRITE:
+		flag = os.O_WRONLY
+	case ninep.ORDWR:
+		flag = os.O_RDWR
+	}
+	return os.OpenFile(filepath.Join(string(m), name), flag, 0)
+}
+
+type ninepFS struct {
+	root *ninep.Fid
+}
+
+func (n ninepFS) open(name string, mode uint8) (io.ReadWriteCloser, error) {
+	return n.root.OpenFile(name, mode)
+}
+
+// namespace returns the plan9port name space directory.
+func namespace() string {
+	if ns := os.Getenv("NAMESPACE"); ns != "" {
+		return ns
+	}
+	disp := os.Getenv("DISPLAY")
+	if disp == "" {
+		disp = ":0.0"
+	}
+	disp = strings.TrimSuffix(disp, ".0")
+	disp = strings.ReplaceAll(disp, "/", "_")
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil && name == "" {
+		name = u.Username
+	}
+	return fmt.Sprintf("/tmp/ns.%s.%s", name, disp)
+}
+
+// mountAcme connects to acme: dir if given, /mnt/acme on Plan 9, and
+// the acme socket in the plan9port name space elsewhere.
+func mountAcme(dir string) (acmeFS, error) {
+	if dir == "" && runtime.GOOS == "plan9" {
+		dir = "/mnt/acme"
+	}
+	if dir != "" {
+		if _, err := os.Stat(filepath.Join(dir, "index")); err != nil {
+			return nil, fmt.Errorf("no acme at %s", dir)
+		}
+		return mountFS(dir), nil
+	}
+
+	c, err := ninep.Dial("unix", filepath.Join(namespace(), "acme"))
+	if err != nil {
+		return nil, fmt.Errorf("acme not running: %v", err)
+	}
+	root, err := c.Attach(os.Getenv("USER"), "")
+	if err != nil {
+		return nil, err
+	}
+	return ninepFS{root}, nil
+}
+
+// window is an acme window opened by this program.
+type window struct {
+	fs    acmeFS
+	id    int
+	ctl   io.ReadWriteCloser
+	event io.ReadWriteCloser
+}
+
+func newWindow(fs acmeFS) (*window, error) {
+	ctl, err := fs.open("new/ctl", ninep.ORDWR)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 128)
+	n, err := ctl.Read(buf)
+	if err != nil {
+		ctl.Close()
+		return nil, err
+	}
+	f := strings.Fields(string(buf[:n]))
+	if len(f) == 0 {
+		ctl.Close()
+		return nil, fmt.Errorf("bad ctl read %q", buf[:n])
+	}
+	id, err := strconv.Atoi(f[0])
+	if err != nil {
+		ctl.Close()
+		return nil, err
+	}
+
+	w := &window{fs: fs, id: id, ctl: ctl}
+	w.event, err = w.open("event", ninep.ORDWR)
+	if err != nil {
+		ctl.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *window) open(file string, mode uint8) (io.ReadWriteCloser, error) {
+	return w.fs.open(fmt.Sprintf("%d/%s", w.id, file), mode)
+}
+
+// Ctl writes control messages, e.g. "name /x" or "clean".
+func (w *window) Ctl(format string, args ...any) error {
+	_, err := fmt.Fprintf(w.ctl, format+"\n", args...)
+	return err
+}
+
+func (w *window) writeFile(file, data string) error {
+	f, err := w.open(file, ninep.OWRITE)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.WriteString(f, data)
+	return err
+}
+
+// SetTag replaces the user part of the tag.
+func (w *window) SetTag(tag string) error {
+	if err := w.Ctl("cleartag"); err != nil {
+		return err
+	}
+	return w.writeFile("tag", " "+tag)
+}
+
+// SetBody replaces the text of the window, marks it clean and shows
+// its start.
+func (w *window) SetBody(text string) error {
+	addr, err := w.open("addr", ninep.OWRITE)
+	if err != nil {
+		return err
+	}
+	defer addr.Close()
+	if _, err := io.WriteString(addr, ","); err != nil {
+		return err
+	}
+	data, err := w.open("data", ninep.OWRITE)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(data, text)
+	data.Close()
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(addr, "#0"); err != nil {
+		return err
+	}
+	return w.Ctl("dot=addr\nclean\nshow")
+}
+
+// Show selects the first match of a regular expression in the body.
+func (w *window) Show(re string) error {
+	addr, err := w.open("addr", ninep.OWRITE)
+	if err != nil {
+		return err
+	}
+	defer addr.Close()
+	if _, err := io.WriteString(addr, "/"+re+"/"); err != nil {
+		return err
+	}
+	return w.Ctl("dot=addr\nshow")
+}
+
+func (w *window) Close() {
+	w.event.Close()
+	w.ctl.Close()
+}
+
+// Del deletes the window.
+func (w *window) Del() {
+	w.Ctl("delete")
+	w.Close()
+}
+
+// event is a message from the event file, see acme(4).
+type event struct {
+	C1, C2 rune
+	Q0, Q1 int
+	Flag   int
+	Text   string
+	Arg    string // chorded argument of an execute event
+}
+
+func readNum(r *bufio.Reader) (int, error) {
+	var s []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if c == ' ' {
+			return strconv.Atoi(string(s))
+		}
+		s = append(s, c)
+	}
+}
+
+func readOne(r *bufio.Reader) (*event, error) {
+	e := &event{}
+	var err error
+	if e.C1, _, err = r.ReadRune(); err != nil {
+		return nil, err
+	}
+	if e.C2, _, err = r.ReadRune(); err != nil {
+		return nil, err
+	}
+	if e.Q0, err = readNum(r); err != nil {
+		return nil, err
+	}
+	if e.Q1, err = readNum(r); err != nil {
+		return nil, err
+	}
+	if e.Flag, err = readNum(r); err != nil {
+		return nil, err
+	}
+	nr, err := readNum(r)
+	if err != nil {
+		return nil, err
+	}
+	var text []rune
+	for range nr {
+		c, _, err := r.ReadRune()
+		if err != nil {
+			return nil, err
+		}
+		text = append(text, c)
+	}
+	e.Text = string(text)
+	if c, err := r.ReadByte(); err != nil || c != '\n' {
+		return nil, fmt.Errorf("malformed event")
+	}
+	return e, nil
+}
+
+// events sends the window's events until the window is closed. The
+// expansions and chorded arguments acme sends as separate messages are
+// folded into the event they belong to.
+func (w *window) events() <-chan *event {
+	ch := make(chan *event)
+	go func() {
+		defer close(ch)
+		r := bufio.NewReader(w.event)
+		for {
+			e, err := readOne(r)
+			if err != nil {
+				return
+			}
+			if (e.C2 == 'x' || e.C2 == 'X' || e.C2 == 'l' || e.C2 == 'L') && e.Flag&2 != 0 {
+				exp, err := readOne(r)
+				if err != nil {
+					return
+				}
+				e.Text = exp.Text
+			}
+			if (e.C2 == 'x' || e.C2 == 'X') && e.Flag&8 != 0 {
+				arg, err := readOne(r)
+				if err != nil {
+					return
+				}
+				if _, err := readOne(r); err != nil {
+					return
+				}
+				e.Arg = arg.Text
+			}
+			ch <- e
+		}
+	}()
+	return ch
+}
+
+// Pass hands an event back to acme for its default action.
+func (w *window) Pass(e *event) error {
+	_, err := fmt.Fprintf(w.event, "%c%c%d %d\n", e.C1, e.C2, e.Q0, e.Q1)
+	return err
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"tlgread/pkg/corpus"
+	"tlgread/pkg/lexicon"
+	"tlgread/pkg/tlgcore"
+)
+
+// lyceum keeps the state of all windows in this process, so that
+// several windows (or users) can browse different authors at once.
+type lyceum struct {
+	fs    acmeFS
+	dirs  []string
+	files map[string]string // tlg0012 -> path
+	names map[string]*corpus.Authors
+
+	greek, latin *lexicon.Dictionary
+
+	mu   sync.Mutex
+	wins map[string]*window // by window name
+	wg   sync.WaitGroup
+}
+
+var (
+	authorRe = regexp.MustCompile(`(?i)^(tlg|lat|civ|cop)?([0-9]{4})$`)
+	workRe   = regexp.MustCompile(`^(?:ID:)?([0-9]+)$`)
+)
+
+func main() {
+	dirs := flag.String("d", ".", "corpus directories, comma separated (e.g. TLG-E,PHI-5)")
+	mnt := flag.String("mnt", "", "acme file system directory (default /mnt/acme on Plan 9, the plan9port name space elsewhere)")
+	analPath := flag.String("a", "greek-analyses.txt", "analyses txt file")
+	idtPath := flag.String("idt", "greek-analyses.idt", "idt file")
+	lsjPath := flag.String("dic", "grc.lsj.xml", "LSJ XML path")
+	lsjidtPath := flag.String("dicidt", "lsj.idt", "LSJ idt file")
+	latAnalPath := flag.String("la", "latin-analyses.txt", "Latin analyses txt file")
+	latIdtPath := flag.String("lidt", "latin-analyses.idt", "Latin idt file")
+	lsPath := flag.String("ldic", "lat.ls.perseus-eng1.xml", "Lewis & Short XML path")
+	lsidtPath := flag.String("ldicidt", "ls.idt", "Lewis & Short idt file")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: acmetlg [flags] [tlg0012 [work [from [to]]]]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	fs, err := mountAcme(*mnt)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	l := &lyceum{
+		fs:    fs,
+		files: make(map[string]string),
+		names: make(map[string]*corpus.Authors),
+		greek: lexicon.OpenDictionary(*analPath, *idtPath, *lsjPath, *lsjidtPath, false),
+		latin: lexicon.OpenDictionary(*latAnalPath, *latIdtPath, *lsPath, *lsidtPath, true),
+		wins:  make(map[string]*window),
+	}
+	for _, dir := range strings.Split(*dirs, ",") {
+		// Files are joined to dir, cleaning it: names is looked up by
+		// the directory of a file.
+		dir = filepath.Clean(dir)
+		files, err := corpus.Files(dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		l.dirs = append(l.dirs, dir)
+		l.names[dir] = corpus.NewAuthors(dir)
+		for _, f := range files {
+			l.files[strings.TrimSuffix(filepath.Base(f), filepath.Ext(f))] = f
+		}
+	}
+
+	args := flag.Args()
+	switch {
+	case len(args) == 0:
+		for _, dir := range l.dirs {
+			l.openAuthors(dir)
+		}
+	case len(args) == 1:
+		l.openWorks(l.resolve(args[0], ""))
+	default:
+		from, to := "", ""
+		if len(args) > 2 {
+			from, to = args[2], args[2]
+		}
+		if len(args) > 3 {
+			to = args[3]
+		}
+		l.openText(l.resolve(args[0], ""), tlgcore.NormalizeID(args[1]), from, to)
+	}
+	l.wg.Wait()
+}
+
+// resolve finds the file of an author ID such as TLG0012, tlg0012 or
+// 0012; a bare number is looked up with the prefix of def.
+func (l *lyceum) resolve(id, def string) string {
+	m := authorRe.FindStringSubmatch(id)
+	if m == nil {
+		return ""
+	}
+	prefix := strings.ToLower(m[1])
+	if prefix == "" {
+		prefix = "tlg"
+		if def != "" {
+			prefix = strings.ToLower(filepath.Base(def)[:3])
+		}
+	}
+	return l.files[prefix+m[2]]
+}
+
+// window returns the window with a name, creating it with a handler
+// for its events if there is none.
+func (l *lyceum) window(name string, handle func(w *window, e *event) bool) (*window, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if w, ok := l.wins[name]; ok {
+		w.Ctl("show")
+		return w, false
+	}
+
+	w, err := newWindow(l.fs)
+	if err != nil {
+		log.Print(err)
+		return nil, false
+	}
+	w.Ctl("name %s", name)
+	l.wins[name] = w
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		for e := range w.events() {
+			if !l.common(w, e) && !handle(w, e) {
+				w.Pass(e)
+			}
+		}
+		l.mu.Lock()
+		delete(l.wins, name)
+		l.mu.Unlock()
+		w.Close()
+	}()
+	return w, true
+}
+
+// common handles the commands every window has: Look with an argument
+// opens the dictionary entry of a word.
+func (l *lyceum) common(w *window, e *event) bool {
+	if e.C2 != 'x' && e.C2 != 'X' {
+		return false
+	}
+	f := strings.Fields(e.Text)
+	if len(f) == 0 || f[0] != "Look" {
+		return false
+	}
+	word := strings.TrimSpace(strings.Join(f[1:], " ") + " " + e.Arg)
+	if word == "" {
+		return false // acme's own Look
+	}
+	l.openDict(word)
+	return true
+}
+
+// look returns the word of a B3 event, or "" for other events.
+func look(e *event) string {
+	if e.C2 != 'l' && e.C2 != 'L' {
+		return ""
+	}
+	return strings.TrimSpace(e.Text)
+}
+
+// execute returns the command and arguments of a B2 event.
+func execute(e *event) (string, []string) {
+	if e.C2 != 'x' && e.C2 != 'X' {
+		return "", nil
+	}
+	f := strings.Fields(e.Text + " " + e.Arg)
+	if len(f) == 0 {
+		return "", nil
+	}
+	return f[0], f[1:]
+}
+
+func (l *lyceum) openAuthors(dir string) {
+	name := "/lyceum/" + filepath.Base(dir) + "/"
+	w, isNew := l.window(name, func(w *window, e *event) bool {
+		if cmd, _ := execute(e); cmd == "Get" {
+			l.loadAuthors(w, dir)
+			return true
+		}
+		if path := l.resolve(look(e), dir); path != "" {
+			l.openWorks(path)
+			return true
+		}
+		return false
+	})
+	if isNew {
+		w.SetTag("Get Look")
+		l.loadAuthors(w, dir)
+	}
+}
+
+func (l *lyceum) loadAuthors(w *window, dir string) {
+	files, err := corpus.Files(dir)
+	if err != nil {
+		w.SetBody(err.Error() + "\n")
+		return
+	}
+	var b strings.Builder
+	for _, f := range files {
+		id := strings.TrimSuffix(filepath.Base(f), filepath.Ext(f))
+		fmt.Fprintf(&b, "%-8s | %s\n", strings.ToUpper(id), l.names[dir].Name(f))
+	}
+	w.SetBody(b.String())
+}
+
+func (l *lyceum) openWorks(path string) {
+	if path == "" {
+		return
+	}
+	id := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	w, isNew := l.window("/lyceum/"+id+"/", func(w *window, e *event) bool {
+		if cmd, _ := execute(e); cmd == "Get" {
+			l.loadWorks(w, path)
+			return true
+		}
+		if m := workRe.FindStringSubmatch(look(e)); m != nil {
+			l.openText(path, m[1], "", "")
+			return true
+		}
+		return false
+	})
+	if isNew {
+		w.SetTag("Get Look")
+		l.loadWorks(w, path)
+	}
+}
+
+func (l *lyceum) loadWorks(w *window, path string) {
+	p, err := tlgcore.OpenText(path)
+	if err != nil {
+		w.SetBody(err.Error() + "\n")
+		return
+	}
+	defer p.Close()
+
+	var works []*tlgcore.WorkMetadata
+	for _, meta := range p.IDTData {
+		works = append(works, meta)
+	}
+	sort.Slice(works, func(i, j int) bool {
+		a, _ := strconv.Atoi(works[i].ID)
+		b, _ := strconv.Atoi(works[j].ID)
+		return a < b
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", l.names[filepath.Dir(path)].Name(path))
+	for _, meta := range works {
+		fmt.Fprintf(&b, "ID:%-4s | %s\n", meta.ID, meta.Title)
+	}
+	w.SetBody(b.String())
+}
+
+func (l *lyceum) openText(path, workID, from, to string) {
+	if path == "" {
+		return
+	}
+	id := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	dict := l.greek
+	if tlgcore.IsLatinName(path) {
+		dict = l.latin
+	}
+
+	w, isNew := l.window("/lyceum/"+id+"/"+workID, func(w *window, e *event) bool {
+		if cmd, args := execute(e); cmd == "Get" {
+			// Get [from [to]] reloads the work or a passage of it.
+			from, to = "", ""
+			if len(args) > 0 {
+				from, to = args[0], args[0]
+			}
+			if len(args) > 1 {
+				to = args[1]
+			}
+			l.loadText(w, path, workID, from, to)
+			return true
+		}
+		if word := look(e); isWord(word) {
+			l.openDictWith(dict, word)
+			return true
+		}
+		return false
+	})
+	if isNew {
+		w.SetTag("Get Look")
+		l.loadText(w, path, workID, from, to)
+	} else if w != nil && from != "" {
+		w.Show("^" + regexp.QuoteMeta(from) + " ")
+	}
+}
+
+func (l *lyceum) loadText(w *window, path, workID, from, to string) {
+	p, err := tlgcore.OpenText(path)
+	if err != nil {
+		w.SetBody(err.Error() + "\n")
+		return
+	}
+	defer p.Close()
+
+	var lines []*tlgcore.Line
+	if from != "" || to != "" {
+		lines, err = p.ExtractRange(workID, from, to)
+	} else {
+		lines, err = p.WorkLines(workID)
+	}
+	if err != nil {
+		w.SetBody(err.Error() + "\n")
+		return
+	}
+
+	var b strings.Builder
+	for _, ln := range lines {
+		if strings.TrimSpace(ln.Text) != "" {
+			b.WriteString(tlgcore.FormatLine(ln))
+		}
+	}
+	w.SetBody(b.String())
+}
+
+// isWord reports whether a B3 selection is a single word.
+func isWord(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsMark(r) && r != '’' && r != '\'' {
+			return false
+		}
+	}
+	return true
+}
+
+func isGreek(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Greek, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// openDict looks a word up in the dictionary of its script.
+func (l *lyceum) openDict(word string) {
+	dict := l.latin
+	if isGreek(word) {
+		dict = l.greek
+	}
+	l.openDictWith(dict, word)
+}
+
+// openDictWith shows the analyses and entries of a word in the
+// dictionary window, which is shared by all texts.
+func (l *lyceum) openDictWith(dict *lexicon.Dictionary, word string) {
+	word = strings.TrimRight(word, "’'")
+	w, isNew := l.window("/lyceum/+dict", func(w *window, e *event) bool {
+		if word := look(e); isWord(word) {
+			l.openDict(word)
+			return true
+		}
+		return false
+	})
+	if w == nil {
+		return
+	}
+	if isNew {
+		w.SetTag("Look")
+	}
+	w.SetBody(dict.Report(word))
+}
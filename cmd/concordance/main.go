@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"tlgread/pkg/corpus"
+	"tlgread/pkg/morph"
+	"tlgread/pkg/tlgcore"
+	"tlgread/pkg/tlgindex"
+	"unicode/utf8"
+)
+
+type jsonEntry struct {
+	Author   string `json:"author"`
+	Title    string `json:"title"`
+	Work     string `json:"work"`
+	Citation string `json:"citation"`
+	URN      string `json:"urn"`
+	Left     string `json:"left"`
+	Hit      string `json:"hit"`
+	Right    string `json:"right"`
+}
+
+func main() {
+	dPath := flag.String("d", ".", "corpus directory (TLG-E or PHI-5)")
+	wordRaw := flag.String("w", "", "word in Beta Code / Greek")
+	strict := flag.Bool("strict", false, "ignore accents, breathings and other diacritics")
+	lemma := flag.Bool("lemma", false, "treat -w as a lemma and search all of its forms")
+	lemmataPath := flag.String("lemmata", "greek-lemmata.txt", "lemmata file for -lemma")
+	analPath := flag.String("a", "greek-analyses.txt", "analyses txt file, for more forms of a -lemma")
+	idtPath := flag.String("idt", "greek-analyses.idt", "idt file of -a")
+	width := flag.Int("width", 40, "characters of context on each side")
+	sortBy := flag.String("sort", "text", "order: text, left or right")
+	format := flag.String("fmt", "text", "output format: text, csv or json")
+	showURN := flag.Bool("urn", false, "print CTS URNs instead of citations (text format)")
+	ixPath := flag.String("index", "", "corpus index directory built by indexer -corpus")
+	workers := flag.Int("j", 0, "number of files scanned in parallel (0: all CPUs)")
+	century := flag.String("century", "", "only works of these centuries, e.g. -5..-4 (negative: B.C.)")
+	genre := flag.String("genre", "", "only works of this genre, e.g. Tragoedia")
+	region := flag.String("region", "", "only authors of this region, e.g. Attica")
+	flag.Parse()
+
+	if *wordRaw == "" {
+		log.Fatal("Usage: ./concordance -d path/to/TLG-E -w word [-lemma] [-sort left|right] [-fmt text|csv|json]\n" +
+			"       [-century -5..-4] [-genre Tragoedia] [-region Attica]")
+	}
+
+	filter, err := corpus.NewFilter(*dPath, *century, *genre, *region)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	forms := make(map[string][]string)
+	if *lemma {
+		analyses, err := morph.OpenAnalyses(*analPath, *idtPath)
+		if err != nil {
+			log.Printf("%v: searching the forms of %s only", err, *lemmataPath)
+			analyses = nil
+		}
+		forms, err = morph.FormKeys(*lemmataPath, analyses, *wordRaw, *strict)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		key := tlgcore.QueryKey(*wordRaw, *strict)
+		if key == "" {
+			log.Fatalf("no word in %q", *wordRaw)
+		}
+		forms[key] = nil
+	}
+
+	match := func(w string) bool {
+		_, ok := forms[tlgcore.WordKey(w, *strict)]
+		return ok
+	}
+
+	var entries []corpus.KWIC
+	if *ixPath != "" {
+		ix, err := tlgindex.Open(*ixPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		works := make(map[string][]string)
+		seen := make(map[string]bool)
+		work := filter.WorkFunc()
+		for key := range forms {
+			postings, err := ix.Lookup(key, *strict)
+			if err != nil {
+				log.Fatal(err)
+			}
+			for _, p := range postings {
+				if !work(p.File, p.Work) {
+					continue
+				}
+				if !seen[p.File+"\t"+p.Work] {
+					seen[p.File+"\t"+p.Work] = true
+					works[p.File] = append(works[p.File], p.Work)
+				}
+			}
+		}
+		entries, err = corpus.ConcordanceWorks(*dPath, works, *width, match)
+	} else {
+		var files []string
+		files, err = corpus.Files(*dPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		entries, err = corpus.ConcordanceFunc(filter.Files(files), *workers, *width, match)
+	}
+	if err != nil {
+		log.Println("Error:", err)
+	}
+	entries = filter.KWIC(entries)
+
+	corpus.SortKWIC(entries, *sortBy)
+	authors := corpus.NewAuthors(*dPath)
+
+	switch *format {
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"author", "title", "work", "citation", "urn", "left", "hit", "right"})
+		for _, e := range entries {
+			w.Write([]string{authors.Name(e.Path), e.Title, e.WorkID, e.Citation, e.URN.String(), e.Left, e.Hit, e.Right})
+		}
+		w.Flush()
+	case "json":
+		out := []jsonEntry{}
+		for _, e := range entries {
+			out = append(out, jsonEntry{authors.Name(e.Path), e.Title, e.WorkID, e.Citation, e.URN.String(), e.Left, e.Hit, e.Right})
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		for _, e := range entries {
+			cit := e.Citation
+			if *showURN {
+				cit = e.URN.String()
+			}
+			pad := strings.Repeat(" ", max(0, *width-utf8.RuneCountInString(e.Left)))
+			fmt.Printf("%s%s  %s  %s | %s, %s %s\n", pad, e.Left, e.Hit, e.Right, authors.Name(e.Path), e.Title, cit)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"tlgread/pkg/lexicon"
+	"tlgread/pkg/morph"
+	"tlgread/pkg/tlgcore"
+)
+
+type glossEntry struct {
+	Lemma    string // Beta Code (or Latin)
+	Headword string
+	Gloss    string
+	Forms    []string
+	First    string // citation of the first occurrence
+	Count    int
+}
+
+// lemmaKey normalizes a lemma for comparison with the known list:
+// homograph numbers ("ei)mi/1") are dropped.
+func lemmaKey(lemma string) string {
+	return strings.TrimRight(tlgcore.WordKey(lemma, false), "0123456789")
+}
+
+func loadKnown(path string) (map[string]bool, error) {
+	known := make(map[string]bool)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		for _, w := range strings.Fields(scanner.Text()) {
+			known[lemmaKey(morph.BetaQuery(w))] = true
+		}
+	}
+	return known, scanner.Err()
+}
+
+func main() {
+	fPath := flag.String("f", "", "TLG .txt")
+	wID := flag.String("w", "", "Work ID")
+	from := flag.String("from", "", "first citation, e.g. 2.100")
+	to := flag.String("to", "", "last citation, e.g. 2.150")
+	urn := flag.String("urn", "", "CTS URN of the passage (instead of -f, -w, -from, -to)")
+	dPath := flag.String("d", ".", "corpus directory used with -urn")
+	analPath := flag.String("a", "greek-analyses.txt", "analyses txt file")
+	idtPath := flag.String("idt", "greek-analyses.idt", "idt file")
+	lsjPath := flag.String("dic", "grc.lsj.xml", "LSJ XML path")
+	lsjidtPath := flag.String("dicidt", "lsj.idt", "LSJ idt file")
+	knownPath := flag.String("known", "", "file of known lemmata to leave out")
+	sortBy := flag.String("sort", "appear", "order: appear or alpha")
+	format := flag.String("fmt", "text", "output format: text or latex")
+	font := flag.String("font", "Gentium Plus", "main font for -fmt latex")
+	glossLen := flag.Int("len", 80, "maximum length of a gloss")
+	isLatin := flag.Bool("lat", false, "use L-S dictionary")
+	flag.Parse()
+
+	if *urn != "" {
+		u, err := tlgcore.ParseURN(*urn)
+		if err != nil {
+			log.Fatal(err)
+		}
+		*fPath = u.Path(*dPath)
+		*wID = u.WorkID()
+		*from, *to = u.From, u.To
+		if *to == "" {
+			*to = u.From
+		}
+	}
+
+	if *fPath == "" || *wID == "" {
+		log.Fatal("Usage: ./glossary -f tlg0012.txt -w 1 [-from 1.1 -to 1.50] [-known list.txt] [-fmt latex]")
+	}
+
+	p, err := tlgcore.OpenText(*fPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer p.Close()
+
+	workID := tlgcore.NormalizeID(*wID)
+	var lines []*tlgcore.Line
+	if *from != "" || *to != "" {
+		lines, err = p.ExtractRange(workID, *from, *to)
+	} else {
+		lines, err = p.WorkLines(workID)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	analyses, err := morph.OpenAnalyses(*analPath, *idtPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	known := make(map[string]bool)
+	if *knownPath != "" {
+		known, err = loadKnown(*knownPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	display := tlgcore.ToGreek
+	if *isLatin {
+		display = func(s string) string { return s }
+	}
+
+	var entries []*glossEntry
+	byLemma := make(map[string]*glossEntry)
+	var unknown []string
+	seenUnknown := make(map[string]bool)
+	shortDefs := make(map[string]string)
+
+	for _, l := range lines {
+		for _, w := range tlgcore.Words(l.Beta) {
+			results, err := analyses.LookupWord(w)
+			if err != nil {
+				if !seenUnknown[w] {
+					seenUnknown[w] = true
+					unknown = append(unknown, display(w))
+				}
+				continue
+			}
+			for _, r := range results {
+				fields := strings.Fields(r.Lemma)
+				if len(fields) == 0 {
+					continue
+				}
+				lemma := fields[0]
+				key := lemmaKey(lemma)
+				if known[key] {
+					continue
+				}
+				if r.ShortDef != "---" && shortDefs[key] == "" {
+					shortDefs[key] = r.ShortDef
+				}
+				e := byLemma[key]
+				if e == nil {
+					e = &glossEntry{Lemma: lemma, First: l.Citation}
+					byLemma[key] = e
+					entries = append(entries, e)
+				}
+				e.Count++
+				form := display(w)
+				if !contains(e.Forms, form) {
+					e.Forms = append(e.Forms, form)
+				}
+			}
+		}
+	}
+
+	lsjIndex := lexicon.LoadLSJIndex(*lsjidtPath)
+	for _, e := range entries {
+		e.Headword = display(e.Lemma)
+		dicEntries, err := lexicon.Lookup(*lsjPath, e.Lemma, lsjIndex, make(map[int64]bool), !*isLatin)
+		if err != nil {
+			log.Println(err)
+		}
+		if len(dicEntries) > 0 {
+			e.Headword = dicEntries[0].Headword
+			e.Gloss = lexicon.ShortGloss(dicEntries[0].Sense, *glossLen)
+		}
+		if e.Gloss == "" {
+			e.Gloss = shortDefs[lemmaKey(e.Lemma)]
+		}
+	}
+
+	if *sortBy == "alpha" {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return tlgcore.WordKey(entries[i].Lemma, true) < tlgcore.WordKey(entries[j].Lemma, true)
+		})
+	}
+
+	title := passageTitle(p, *fPath, workID, *from, *to)
+	if *format == "latex" {
+		writeLaTeX(title, *font, entries, unknown)
+	} else {
+		writeText(title, entries, unknown)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func passageTitle(p *tlgcore.Parser, path, workID, from, to string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	author := tlgcore.GetAuthorName(filepath.Join(filepath.Dir(path), "authtab.dir"), base)
+	title := author
+	if meta := p.IDTData[workID]; meta != nil {
+		title += ", " + meta.Title
+	}
+	switch {
+	case from != "" && to != "" && from != to:
+		title += " " + from + "–" + to
+	case from != "":
+		title += " " + from
+	}
+	return title
+}
+
+func writeText(title string, entries []*glossEntry, unknown []string) {
+	fmt.Printf("Glossary: %s\n", title)
+	fmt.Println("----------------------------------------")
+	for _, e := range entries {
+		fmt.Printf("%s — %s [%s: %s]\n", e.Headword, e.Gloss, e.First, strings.Join(e.Forms, ", "))
+	}
+	if len(unknown) > 0 {
+		fmt.Println("----------------------------------------")
+		fmt.Printf("Not analysed: %s\n", strings.Join(unknown, ", "))
+	}
+}
+
+var latexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`, `&`, `\&`, `%`, `\%`, `$`, `\$`, `#`, `\#`,
+	`_`, `\_`, `{`, `\{`, `}`, `\}`, `~`, `\textasciitilde{}`, `^`, `\textasciicircum{}`,
+)
+
+func writeLaTeX(title, font string, entries []*glossEntry, unknown []string) {
+	fmt.Println(`\documentclass[11pt]{article}`)
+	fmt.Println(`\usepackage{fontspec}`)
+	fmt.Printf("\\setmainfont{%s}\n", font)
+	fmt.Println(`\usepackage[margin=2cm]{geometry}`)
+	fmt.Println(`\begin{document}`)
+	fmt.Printf("\\section*{%s}\n", latexEscaper.Replace(title))
+	fmt.Println(`\begin{description}`)
+	for _, e := range entries {
+		fmt.Printf("\\item[%s] %s \\hfill {\\small %s: %s}\n", latexEscaper.Replace(e.Headword),
+			latexEscaper.Replace(e.Gloss), latexEscaper.Replace(e.First), latexEscaper.Replace(strings.Join(e.Forms, ", ")))
+	}
+	fmt.Println(`\end{description}`)
+	if len(unknown) > 0 {
+		fmt.Printf("\\noindent\\textit{Not analysed:} %s\n", latexEscaper.Replace(strings.Join(unknown, ", ")))
+	}
+	fmt.Println(`\end{document}`)
+}
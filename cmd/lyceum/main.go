@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"tlgread/pkg/lyceum"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: lyceum command [flags] [args]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, c := range lyceum.Commands {
+		fmt.Fprintf(os.Stderr, "  %-7s %-24s %s\n", c.Name, c.Args, c.Summary)
+	}
+	fmt.Fprintf(os.Stderr, "  %-7s %-24s %s\n", "config", "", "print the configuration")
+	fmt.Fprintf(os.Stderr, "\nconfig file: %s\n", lyceum.ConfigPath())
+	fmt.Fprintln(os.Stderr, "Run 'lyceum command -h' for the flags of a command.")
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("lyceum: ")
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cfg, err := lyceum.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	name := os.Args[1]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		usage()
+		return
+	case "config":
+		cfg.WriteTo(os.Stdout)
+		return
+	}
+
+	c := lyceum.Lookup(name)
+	if c == nil {
+		usage()
+		os.Exit(2)
+	}
+	if err := c.Run(cfg, append([]string{"lyceum " + name}, os.Args[2:]...)); err != nil {
+		log.Fatal(err)
+	}
+}
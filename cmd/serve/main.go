@@ -0,0 +1,14 @@
+// Command serve is the old name of 'lyceum serve', kept for existing scripts.
+package main
+
+import (
+	"log"
+	"os"
+	"tlgread/pkg/lyceum"
+)
+
+func main() {
+	if err := lyceum.Serve(lyceum.Legacy(), os.Args); err != nil {
+		log.Fatal(err)
+	}
+}
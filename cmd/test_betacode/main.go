@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"tlgread/pkg/tlgcore"
+)
+
+// Conformance cases for the Beta Code converter: letters and
+// diacritics, then every punctuation (%), special character (#),
+// bracket and quotation code the converter knows, each followed by a
+// letter to check that the code's digits are consumed.
+var cases = []struct {
+	beta, want string
+}{
+	// Letters, breathings, accents, iota subscript, diaeresis.
+	{"mh=nin a)/eide qea/", "μῆνιν ἄειδε θεά"},
+	{"*)axilh=os", "Ἀχιλῆος"},
+	{"*(ODUSSEU/S", "Ὁδυσσεύς"},
+	{"lo/gos", "λόγος"},
+	{"tw=|", "τῷ"},
+	{"ai)/", "αἴ"},
+	{"a)i+/", "ἀΐ"},
+
+	// Final sigma before quotation marks, brackets and signs.
+	{`"a)/nqrwpos" ei)=pen`, "“ἄνθρωπος” εἶπεν"},
+	{`"6lo/gos"6`, "«λόγος»"},
+	{`"7lo/gos"7`, "‹λόγος›"},
+	{"[2lo/gos]2", "⟨λόγος⟩"},
+	{"[4lo/gos]4", "⟦λόγος⟧"},
+	{"lo/gos%13", "λόγος‡"},
+	{"S#", "σʹ"}, // the numeral 200
+
+	// %: punctuation and critical signs.
+	{"%A", "†α"},
+	{"%1A", "?α"},
+	{"%2A", "*α"},
+	{"%3A", "/α"},
+	{"%4A", "!α"},
+	{"%5A", "|α"},
+	{"%6A", "=α"},
+	{"%7A", "+α"},
+	{"%8A", "%α"},
+	{"%9A", "&α"},
+	{"%10A", ":α"},
+	{"%11A", "•α"},
+	{"%12A", "*α"},
+	{"%13A", "‡α"},
+	{"%14A", "§α"},
+	{"%15A", "ˈα"},
+	{"%16A", "¦α"},
+	{"%17A", "‖α"},
+	{"%18A", "'α"},
+	{"%19A", "-α"},
+	{"%41A", "-α"},
+	{"%43A", "×α"},
+	{"%100A", ";α"},
+	{"%101A", "#α"},
+	{"%102A", "‘α"},
+	{"%103A", "\\α"},
+	{"%104A", "^α"},
+	{"%105A", "|||α"},
+	{"%106A", "≈α"},
+	{"%107A", "~α"},
+	{"%108A", "±α"},
+	{"%109A", "·α"},
+	{"%99A", "α"}, // unknown: nothing
+
+	// #: numeral sign, numeral letters, critical signs.
+	{"A#", "αʹ"},
+	{"#1A", "ϟα"},
+	{"#2A", "ϛα"},
+	{"#3A", "ϙα"},
+	{"#5A", "ϡα"},
+	{"#6A", "⊢α"},
+	{"#8A", "⸏α"},
+	{"#12A", "—α"},
+	{"#13A", "※α"},
+	{"#15A", ">α"},
+	{"#17A", "/α"},
+	{"#18A", "<α"},
+	{"#22B", "͵β"}, // 2000
+
+	// #: metrical symbols.
+	{"#50#51#52", "–⏑×"},
+	{"#53#54#55#56#57", "⏓⏒⏔⏕⏖"},
+	{"#58#59#60", "⏗⏘⏙"},
+
+	// #: signs of papyri and manuscripts.
+	{"#70A", "⸎α"},
+	{"#71A", "⸐α"},
+	{"#72A", "⸑α"},
+	{"#73A", "÷α"},
+	{"#74A", "⁎α"},
+	{"#99A", "α"},
+
+	// [ ]: brackets.
+	{"[A]", "[α]"},
+	{"[1A]1", "(α)"},
+	{"[2A]2", "⟨α⟩"},
+	{"[3A]3", "{α}"},
+	{"[4A]4", "⟦α⟧"},
+	{"[5A]5", "⌊α⌋"},
+	{"[6A]6", "⌈α⌉"},
+	{"[7A]7", "⌈α⌋"},
+	{"[8A]8", "⌊α⌉"},
+	{"[9A]9", "˙α˙"},
+	{"[99A]99", "[α]"}, // unknown: plain brackets
+
+	// ": quotation marks; paired marks open and close in turn.
+	{`"A"`, "“α”"},
+	{`"6A"6`, "«α»"},
+	{`"7A"7`, "‹α›"},
+	{`"1A"2`, "„α“"},
+	{`"2A"8`, "“α”"},
+	{`"3A"5`, "‘α’"},
+	{`"4A"3`, "‚α‘"},
+	{`"6A"8`, "«α”"},
+}
+
+func main() {
+	fmt.Println("=== Beta Code Conformance Test ===")
+	failed := 0
+	for _, c := range cases {
+		got := tlgcore.ToGreek(c.beta)
+		if got != c.want {
+			fmt.Printf("[FAIL] %-12s -> %q, want %q\n", c.beta, got, c.want)
+			failed++
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("[FAIL] %d of %d cases\n", failed, len(cases))
+		os.Exit(1)
+	}
+	fmt.Printf("[PASS] %d cases\n", len(cases))
+}
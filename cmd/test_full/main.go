@@ -4,11 +4,14 @@ import (
 	"flag"
 	"fmt"
 	"math/rand"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+	"tlgread/pkg/ninep"
 	"tlgread/pkg/tlgcore"
+	"tlgread/pkg/tlgfs"
 )
 
 func main() {
@@ -21,17 +24,17 @@ func main() {
 
 	// 1. Locate Author Table
 	authPath := filepath.Join(*dirPath, "authtab.dir")
-	hasAuth := false
-	if _, err := os.Stat(authPath); err == nil {
-		hasAuth = true
-		fmt.Println("[PASS] Found authtab.dir")
+	authors, err := tlgcore.ReadAuthorTable(authPath)
+	hasAuth := err == nil
+	if hasAuth {
+		fmt.Printf("[PASS] Found authtab.dir (%d authors)\n", len(authors.Records))
 	} else {
 		fmt.Println("[WARN] authtab.dir not found. Author names will be 'Unknown'.")
 	}
 
 	// 2. Find all IDT files
 	var idtFiles []string
-	err := filepath.Walk(*dirPath, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(*dirPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -108,9 +111,10 @@ func main() {
 		// TEST B: Check Author (if available)
 		author := "N/A"
 		if hasAuth {
-			author = tlgcore.GetAuthorName(authPath, base)
-			if author == "Unknown" || author == "" {
+			author = authors.Name(base)
+			if authors.Lookup(base) == nil {
 				// Not necessarily a fail, but worth noting
+				author = "(not in authtab.dir)"
 			}
 		}
 
@@ -168,6 +172,288 @@ func main() {
 		}
 	}
 
+	// 5. Citation ranges
+	fmt.Printf("Testing citation ranges ... ")
+	if msg, err := testRanges(*dirPath); err != nil {
+		fmt.Printf("[FAIL] %v\n", err)
+		failCount++
+	} else {
+		fmt.Printf("[PASS] %s\n", msg)
+		passCount++
+	}
+
+	// 6. Author search
+	fmt.Printf("Testing author search ... ")
+	if msg, err := testAuthorSearch(); err != nil {
+		fmt.Printf("[FAIL] %v\n", err)
+		failCount++
+	} else {
+		fmt.Printf("[PASS] %s\n", msg)
+		passCount++
+	}
+
+	// 7. TLG canon
+	fmt.Printf("Testing canon ... ")
+	if msg, err := testCanon(*dirPath); err != nil {
+		fmt.Printf("[FAIL] %v\n", err)
+		failCount++
+	} else {
+		fmt.Printf("[PASS] %s\n", msg)
+		passCount++
+	}
+
+	// 8. Serve the directory over 9P and read it back
+	fmt.Printf("Testing 9P file tree ... ")
+	if msg, err := test9P(*dirPath); err != nil {
+		fmt.Printf("[FAIL] %v\n", err)
+		failCount++
+	} else {
+		fmt.Printf("[PASS] %s\n", msg)
+		passCount++
+	}
+
 	fmt.Println("---------------------------------------------------")
 	fmt.Printf("Test Complete. Passed: %d, Failed: %d\n", passCount, failCount)
 }
+
+// rangeCases are citations and the bounds they must lie in or not. A
+// bound without a Stephanus letter covers its whole page.
+var rangeCases = []struct {
+	cit, from, to string
+	in            bool
+}{
+	{"2.37", "2", "2", true},
+	{"2.37", "1.10", "2.36", false},
+	{"328a", "327a", "328", true},
+	{"328e", "327a", "328", true},
+	{"329a", "327a", "328", false},
+	{"327a", "327", "", true},
+	{"327a", "327b", "", false},
+	{"327c.5", "327b", "327c", true},
+	{"327c.5", "327b", "327c.4", false},
+}
+
+// testRanges checks InRange, and ExtractRange on the Republic (Plato,
+// tlg0059 work 30) if the directory has it.
+func testRanges(dir string) (string, error) {
+	for _, c := range rangeCases {
+		if got := tlgcore.InRange(c.cit, c.from, c.to); got != c.in {
+			return "", fmt.Errorf("InRange(%q, %q, %q) = %v", c.cit, c.from, c.to, got)
+		}
+	}
+	msg := fmt.Sprintf("%d InRange cases", len(rangeCases))
+
+	p, err := tlgcore.OpenText(filepath.Join(dir, "tlg0059.txt"))
+	if err != nil {
+		return msg, nil
+	}
+	defer p.Close()
+	lines, err := p.ExtractRange("030", "327a", "328")
+	if err != nil {
+		return "", err
+	}
+	last := lines[len(lines)-1].Citation
+	if !strings.HasPrefix(last, "328e") {
+		return "", fmt.Errorf("Republic 327a-328 ends at %s, not 328e", last)
+	}
+	return fmt.Sprintf("%s; Republic 327a-328: %d lines", msg, len(lines)), nil
+}
+
+// authorQueries are queries in Latin and Greek letters and the author
+// they must find first.
+var authorQueries = []struct {
+	query, id string
+}{
+	{"thucyd", "TLG0003"},
+	{"Θουκυδ", "TLG0003"},
+	{"Thoukydides", "TLG0003"},
+	{"Πλάτων", "TLG0059"},
+	{"plat", "TLG0059"},
+	{"Ζήνων", "TLG0641"},
+	{"Σόλων", "TLG0263"},
+	{"Homer", "TLG0012"},
+	{"Ὅμηρος", "TLG0012"},
+}
+
+// testAuthorSearch runs authorQueries against a few records of
+// authtab.dir.
+func testAuthorSearch() (string, error) {
+	t := &tlgcore.AuthorTable{}
+	for _, r := range []struct{ id, name string }{
+		{"TLG0003", "Thucydides"},
+		{"TLG0012", "Homerus"},
+		{"TLG0059", "Plato"},
+		{"TLG0263", "Solon"},
+		{"TLG0641", "Zeno"},
+		{"TLG1799", "Plotinus"},
+	} {
+		t.Records = append(t.Records, &tlgcore.AuthorRecord{ID: r.id, Name: r.name})
+	}
+	for _, q := range authorQueries {
+		m := t.Search(q.query)
+		if len(m) == 0 {
+			return "", fmt.Errorf("%q finds nothing, want %s", q.query, q.id)
+		}
+		if m[0].Record.ID != q.id {
+			return "", fmt.Errorf("%q finds %s first, want %s", q.query, m[0].Record.ID, q.id)
+		}
+	}
+	return fmt.Sprintf("%d queries", len(authorQueries)), nil
+}
+
+// canonSample is a piece of doccan2.txt: entries cited by author and
+// work (work 0 for the author), each starting with its heading.
+var canonSample = []struct {
+	author, work, text string
+}{
+	{"0012", "0", "HOMERUS Epic."},
+	{"0012", "0", "Dat: 8 B.C.?"},
+	{"0012", "0", "Geo: Ionia"},
+	{"0012", "1", "ILIAS"},
+	{"0012", "1", "Cit: Book/line"},
+	{"0012", "1", "Ed: T.W. Allen, Homeri Ilias, vols. 2-3. Oxford: Clarendon Press,"},
+	{"0012", "1", "1931: 1:1-356; 2:1-372."},
+	{"0012", "1", "(Cod: 115,477: Epic.)"},
+	{"0003", "0", "THUCYDIDES Hist."},
+	{"0003", "0", "5 B.C."},
+	{"0003", "1", "HISTORIAE"},
+	{"0003", "1", "H.S. Jones and J.E. Powell, eds., Thucydidis historiae, 2 vols. Oxford, 1942."},
+	{"0003", "1", "(Cod: 150,173: Hist.)"},
+}
+
+// testCanon parses canonSample, and the canon of the directory if it
+// has one.
+func testCanon(dir string) (string, error) {
+	var lines []*tlgcore.Line
+	for _, l := range canonSample {
+		lines = append(lines, &tlgcore.Line{AuthorID: l.author, WorkID: l.work, Text: l.text})
+	}
+	c := tlgcore.ParseCanonLines(lines)
+
+	check := func(what, got, want string) error {
+		if got != want {
+			return fmt.Errorf("canon sample: %s is %q, want %q", what, got, want)
+		}
+		return nil
+	}
+	a, w := c.Author("tlg0012"), c.Work("tlg0012", "001")
+	t := c.Work("tlg0003", "1")
+	if a == nil || w == nil || t == nil {
+		return "", fmt.Errorf("canon sample: missing entries")
+	}
+	for _, err := range []error{
+		check("author", a.Name+" | "+a.Epithet, "HOMERUS | Epic."),
+		check("author date", a.Date, "8 B.C.?"),
+		check("region", a.Region, "Ionia"),
+		check("title", w.Title, "ILIAS"),
+		check("citation", w.Citation, "Book/line"),
+		check("edition", w.Edition, "T.W. Allen, Homeri Ilias, vols. 2-3. Oxford: Clarendon Press, 1931: 1:1-356; 2:1-372."),
+		check("editor", w.Editor, "T.W. Allen"),
+		check("genre", w.Genre, "Epic."),
+		check("word count", fmt.Sprint(w.Words), "115477"),
+		check("date", c.Author("tlg0003").Date, "5 B.C."),
+		check("editor", t.Editor, "H.S. Jones and J.E. Powell"),
+	} {
+		if err != nil {
+			return "", err
+		}
+	}
+	msg := "sample parsed"
+
+	if _, err := os.Stat(filepath.Join(dir, "doccan2.txt")); err != nil {
+		return msg, nil
+	}
+	c, err := tlgcore.ReadCanon(dir)
+	if err != nil {
+		return "", err
+	}
+	works, dated := 0, 0
+	for _, a := range c.Authors {
+		works += len(a.Works)
+		if a.Date != "" {
+			dated++
+		}
+	}
+	if len(c.Authors) == 0 {
+		return "", fmt.Errorf("no entries in the canon of %s", dir)
+	}
+	return fmt.Sprintf("%s; canon: %d authors (%d dated), %d works", msg, len(c.Authors), dated, works), nil
+}
+
+// test9P reads the first work of the first author through a 9P client
+// and compares it with the text the parser extracts directly.
+func test9P(dir string) (string, error) {
+	root, err := tlgfs.New(tlgfs.Config{Corpus: []string{dir}})
+	if err != nil {
+		return "", err
+	}
+	srvConn, cliConn := net.Pipe()
+	go (&ninep.Server{Root: root}).Serve(srvConn)
+
+	c, err := ninep.NewClient(cliConn)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	fsys, err := c.Attach("test", "")
+	if err != nil {
+		return "", err
+	}
+
+	coll := "tlg"
+	authors, err := fsys.ReadFile("tlg/authors")
+	if err != nil {
+		coll = "phi"
+		authors, err = fsys.ReadFile("phi/authors")
+	}
+	if ninep.IsNotExist(err) {
+		return "no tlg or phi files, skipped", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(authors))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty %s/authors", coll)
+	}
+	author := fields[0]
+
+	works, err := fsys.ReadFile(coll + "/" + author + "/works")
+	if err != nil {
+		return "", err
+	}
+	fields = strings.Fields(strings.TrimPrefix(string(works), "ID:"))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("no works for %s", author)
+	}
+	work := fields[0]
+
+	text, err := fsys.ReadFile(coll + "/" + author + "/" + work + "/text")
+	if err != nil {
+		return "", err
+	}
+
+	prefix := coll
+	if coll == "phi" {
+		prefix = "lat"
+	}
+	p, err := tlgcore.OpenText(filepath.Join(dir, prefix+author+".txt"))
+	if err != nil {
+		return "", err
+	}
+	defer p.Close()
+	lines, err := p.WorkLines(work)
+	if err != nil {
+		return "", err
+	}
+	var want strings.Builder
+	for _, l := range lines {
+		if strings.TrimSpace(l.Text) != "" {
+			want.WriteString(tlgcore.FormatLine(l))
+		}
+	}
+	if string(text) != want.String() {
+		return "", fmt.Errorf("%s/%s/%s/text differs from the parser output", coll, author, work)
+	}
+	return fmt.Sprintf("%s/%s/%s/text: %d bytes", coll, author, work, len(text)), nil
+}
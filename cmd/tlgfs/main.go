@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"strings"
+	"tlgread/pkg/lexicon"
+	"tlgread/pkg/ninep"
+	"tlgread/pkg/tlgfs"
+)
+
+func main() {
+	dirs := flag.String("d", ".", "corpus directories, comma separated (e.g. TLG-E,PHI-5)")
+	network := flag.String("net", "tcp", "network to listen on: tcp or unix")
+	addr := flag.String("addr", "localhost:5640", "listen address (socket path for unix)")
+	analPath := flag.String("a", "greek-analyses.txt", "analyses txt file")
+	idtPath := flag.String("idt", "greek-analyses.idt", "idt file")
+	lsjPath := flag.String("dic", "grc.lsj.xml", "LSJ XML path")
+	lsjidtPath := flag.String("dicidt", "lsj.idt", "LSJ idt file")
+	latAnalPath := flag.String("la", "latin-analyses.txt", "Latin analyses txt file")
+	latIdtPath := flag.String("lidt", "latin-analyses.idt", "Latin idt file")
+	lsPath := flag.String("ldic", "lat.ls.perseus-eng1.xml", "Lewis & Short XML path")
+	lsidtPath := flag.String("ldicidt", "ls.idt", "Lewis & Short idt file")
+	debug := flag.Bool("D", false, "log 9P messages")
+	flag.Parse()
+
+	root, err := tlgfs.New(tlgfs.Config{
+		Corpus: strings.Split(*dirs, ","),
+		Greek:  lexicon.OpenDictionary(*analPath, *idtPath, *lsjPath, *lsjidtPath, false),
+		Latin:  lexicon.OpenDictionary(*latAnalPath, *latIdtPath, *lsPath, *lsidtPath, true),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	srv := &ninep.Server{Root: root, Debug: *debug}
+	log.Printf("serving 9P on %s!%s", *network, *addr)
+	log.Fatal(srv.ListenAndServe(*network, *addr))
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"tlgread/pkg/corpus"
+	"tlgread/pkg/morph"
+	"tlgread/pkg/tlgcore"
+	"tlgread/pkg/tlgindex"
+)
+
+func main() {
+	dPath := flag.String("d", ".", "corpus directory (TLG-E or PHI-5)")
+	wordRaw := flag.String("w", "", "word in Beta Code / Greek")
+	strict := flag.Bool("strict", false, "ignore accents, breathings and other diacritics")
+	workers := flag.Int("j", 0, "number of files scanned in parallel (0: all CPUs)")
+	showURN := flag.Bool("urn", false, "print CTS URNs instead of citations")
+	ixPath := flag.String("index", "", "corpus index directory built by indexer -corpus")
+	lemma := flag.Bool("lemma", false, "treat -w as a lemma and search all of its forms")
+	lemmataPath := flag.String("lemmata", "greek-lemmata.txt", "lemmata file for -lemma")
+	analPath := flag.String("a", "greek-analyses.txt", "analyses txt file, for more forms of a -lemma and their analyses")
+	idtPath := flag.String("idt", "greek-analyses.idt", "idt file of -a")
+	century := flag.String("century", "", "only works of these centuries, e.g. -5..-4 (negative: B.C.)")
+	genre := flag.String("genre", "", "only works of this genre, e.g. Tragoedia")
+	region := flag.String("region", "", "only authors of this region, e.g. Attica")
+	flag.Parse()
+
+	if *wordRaw == "" {
+		log.Fatal("Usage: ./tlgsearch -d path/to/TLG-E -w word [-strict] [-urn] [-index dir] [-lemma]\n" +
+			"       [-century -5..-4] [-genre Tragoedia] [-region Attica]")
+	}
+
+	filter, err := corpus.NewFilter(*dPath, *century, *genre, *region)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// forms maps every searched word key to its analyses.
+	forms := make(map[string][]string)
+	var analyses *morph.Analyses
+	if *lemma {
+		var err error
+		if analyses, err = morph.OpenAnalyses(*analPath, *idtPath); err != nil {
+			log.Printf("%v: searching the forms of %s only", err, *lemmataPath)
+			analyses = nil
+		}
+		forms, err = morph.FormKeys(*lemmataPath, analyses, *wordRaw, *strict)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		key := tlgcore.QueryKey(*wordRaw, *strict)
+		if key == "" {
+			log.Fatalf("no word in %q", *wordRaw)
+		}
+		forms[key] = nil
+	}
+
+	var hits []corpus.Hit
+	if *ixPath != "" {
+		ix, err := tlgindex.Open(*ixPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var postings []tlgindex.Posting
+		work := filter.WorkFunc()
+		for key := range forms {
+			p, err := ix.Lookup(key, *strict)
+			if err != nil {
+				log.Fatal(err)
+			}
+			for _, posting := range p {
+				if work(posting.File, posting.Work) {
+					postings = append(postings, posting)
+				}
+			}
+		}
+		hits, err = tlgindex.Hits(*dPath, postings)
+		if err != nil {
+			log.Println("Error:", err)
+		}
+	} else {
+		files, err := corpus.Files(*dPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		hits, err = corpus.SearchFunc(filter.Files(files), *workers, func(w string) bool {
+			_, ok := forms[tlgcore.WordKey(w, *strict)]
+			return ok
+		})
+		if err != nil {
+			log.Println("Error:", err)
+		}
+		hits = filter.Hits(hits)
+	}
+
+	authors := corpus.NewAuthors(*dPath)
+
+	for _, h := range hits {
+		author := authors.Name(h.Path)
+
+		cit := h.Line.Citation
+		if *showURN {
+			cit = h.URN.String()
+		}
+
+		if !*lemma {
+			fmt.Printf("%s | %s | %s | %s\n", author, h.Title, cit, strings.TrimSpace(h.Line.Text))
+			continue
+		}
+
+		form := h.Word
+		if !tlgcore.IsLatinName(h.Path) {
+			form = tlgcore.ToGreek(form)
+		}
+		fmt.Printf("%s | %s | %s | %s (%s) | %s\n", author, h.Title, cit, form,
+			analysis(forms, analyses, h.Word, *strict), strings.TrimSpace(h.Line.Text))
+	}
+	fmt.Printf("%d lines found.\n", len(hits))
+}
+
+// analysis describes how a matched word form was parsed.
+func analysis(forms map[string][]string, analyses *morph.Analyses, word string, strict bool) string {
+	if a := forms[tlgcore.WordKey(word, strict)]; len(a) > 0 && strings.Join(a, "") != "" {
+		return strings.Join(a, "; ")
+	}
+	if analyses == nil {
+		return "?"
+	}
+	results, err := analyses.Lookup(tlgcore.WordKey(word, false))
+	if err != nil {
+		return "?"
+	}
+	var parts []string
+	for _, r := range results {
+		parts = append(parts, r.Morphology)
+	}
+	return strings.Join(parts, "; ")
+}
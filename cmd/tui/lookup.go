@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"tlgread/pkg/lexicon"
+)
+
+// lookup returns the analyses and dictionary entries of a word as
+// lines of text.
+func lookup(d *lexicon.Dictionary, word string) []string {
+	results, err := d.Analyze(word)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: morphology not found.", word)}
+	}
+
+	var out []string
+	for _, r := range results {
+		out = append(out, d.FormatAnalysis(word, r))
+	}
+	entries, err := d.Define(word)
+	for _, e := range entries {
+		out = append(out, "", fmt.Sprintf("[ENTRY: %s]", e.Headword))
+		out = append(out, strings.Split(e.Text(), "\n")...)
+	}
+	if err != nil {
+		out = append(out, err.Error())
+	}
+	return out
+}
+
+// wrap breaks lines longer than width at spaces.
+func wrap(lines []string, width int) []string {
+	var out []string
+	for _, l := range lines {
+		for utf8.RuneCountInString(l) > width {
+			r := []rune(l)
+			cut := width
+			for i := width; i > width/2; i-- {
+				if r[i] == ' ' {
+					cut = i
+					break
+				}
+			}
+			out = append(out, string(r[:cut]))
+			l = strings.TrimLeft(string(r[cut:]), " ")
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+// tokens returns the byte ranges of the words in a line of text. An
+// apostrophe of elision belongs to the word before it.
+func tokens(text string) [][2]int {
+	var toks [][2]int
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsMark(r) || (start >= 0 && (r == '’' || r == '\''))
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			toks = append(toks, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		toks = append(toks, [2]int{start, len(text)})
+	}
+	return toks
+}
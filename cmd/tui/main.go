@@ -0,0 +1,600 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"tlgread/pkg/corpus"
+	"tlgread/pkg/lexicon"
+	"tlgread/pkg/tlgcore"
+)
+
+type item struct {
+	id    string
+	label string
+}
+
+// list is a selectable list of authors or works.
+type list struct {
+	title  string
+	all    []item
+	items  []item // after filtering
+	sel    int
+	top    int
+	filter string
+}
+
+func (l *list) setFilter(f string) {
+	l.filter = f
+	l.items = nil
+	f = strings.ToLower(f)
+	for _, it := range l.all {
+		if strings.Contains(strings.ToLower(it.label), f) {
+			l.items = append(l.items, it)
+		}
+	}
+	l.sel, l.top = 0, 0
+}
+
+// reader shows the lines of a work with a word cursor.
+type reader struct {
+	title string
+	lines []*tlgcore.Line
+	toks  [][][2]int
+	rich  [][]span // lines styled from their formatting codes
+	cur   int      // current line
+	word  int      // current word of the line, -1 if it has none
+	top   int
+	query string // last search key
+}
+
+type mode int
+
+const (
+	modeAuthors mode = iota
+	modeWorks
+	modeText
+)
+
+type app struct {
+	term *terminal
+	mode mode
+	dir  string
+
+	authors *list
+	works   *list
+	text    *reader
+	parser  *tlgcore.Parser
+
+	greek, latin *lexicon.Dictionary
+	dict         []string // dictionary pane, nil if closed
+	dictTop      int
+	dictFocus    bool
+
+	prompt   string // prompt label, "" if not prompting
+	input    string
+	onInput  func(string)
+	status   string
+	quitting bool
+}
+
+func main() {
+	dPath := flag.String("d", ".", "corpus directory")
+	fPath := flag.String("f", "", "TLG .txt to open")
+	wID := flag.String("w", "", "Work ID to open")
+	at := flag.String("at", "", "citation to jump to, e.g. 2.100")
+	analPath := flag.String("a", "greek-analyses.txt", "analyses txt file")
+	idtPath := flag.String("idt", "greek-analyses.idt", "idt file")
+	lsjPath := flag.String("dic", "grc.lsj.xml", "LSJ XML path")
+	lsjidtPath := flag.String("dicidt", "lsj.idt", "LSJ idt file")
+	latAnalPath := flag.String("la", "latin-analyses.txt", "Latin analyses txt file")
+	latIdtPath := flag.String("lidt", "latin-analyses.idt", "Latin idt file")
+	lsPath := flag.String("ldic", "lat.ls.perseus-eng1.xml", "Lewis & Short XML path")
+	lsidtPath := flag.String("ldicidt", "ls.idt", "Lewis & Short idt file")
+	flag.Parse()
+
+	a := &app{dir: *dPath}
+	a.greek = lexicon.OpenDictionary(*analPath, *idtPath, *lsjPath, *lsjidtPath, false)
+	a.latin = lexicon.OpenDictionary(*latAnalPath, *latIdtPath, *lsPath, *lsidtPath, true)
+
+	if *fPath != "" {
+		if err := a.openWorks(*fPath); err != nil {
+			log.Fatal(err)
+		}
+		if *wID != "" {
+			if err := a.openText(tlgcore.NormalizeID(*wID)); err != nil {
+				log.Fatal(err)
+			}
+			if *at != "" {
+				a.jump(*at)
+			}
+		}
+	} else if err := a.openAuthors(); err != nil {
+		log.Fatal(err)
+	}
+
+	t, err := openTerminal()
+	if err != nil {
+		log.Fatal(err)
+	}
+	a.term = t
+	a.run()
+	t.Close()
+	if a.parser != nil {
+		a.parser.Close()
+	}
+}
+
+func (a *app) openAuthors() error {
+	files, err := corpus.Files(a.dir)
+	if err != nil {
+		return err
+	}
+	names := corpus.NewAuthors(a.dir)
+	l := &list{title: a.dir}
+	for _, f := range files {
+		id := strings.TrimSuffix(filepath.Base(f), filepath.Ext(f))
+		l.all = append(l.all, item{id: f, label: fmt.Sprintf("%-8s %s", id, names.Name(f))})
+	}
+	l.setFilter("")
+	a.authors = l
+	a.mode = modeAuthors
+	return nil
+}
+
+func (a *app) openWorks(path string) error {
+	p, err := tlgcore.OpenText(path)
+	if err != nil {
+		return err
+	}
+	if a.parser != nil {
+		a.parser.Close()
+	}
+	a.parser = p
+
+	name := corpus.NewAuthors(filepath.Dir(path)).Name(path)
+	l := &list{title: name}
+	for _, meta := range p.IDTData {
+		l.all = append(l.all, item{id: meta.ID, label: fmt.Sprintf("ID:%-4s | %s", meta.ID, meta.Title)})
+	}
+	sort.Slice(l.all, func(i, j int) bool {
+		x, _ := strconv.Atoi(l.all[i].id)
+		y, _ := strconv.Atoi(l.all[j].id)
+		return x < y
+	})
+	if len(l.all) == 0 {
+		list, err := p.ExtractList(p.IDTData)
+		if err != nil {
+			return err
+		}
+		for _, s := range list {
+			id := strings.TrimSpace(strings.TrimPrefix(strings.Split(s, "|")[0], "ID:"))
+			l.all = append(l.all, item{id: id, label: s})
+		}
+	}
+	l.setFilter("")
+	a.works = l
+	a.mode = modeWorks
+	return nil
+}
+
+func (a *app) openText(workID string) error {
+	lines, err := a.parser.WorkLines(workID)
+	if err != nil {
+		return err
+	}
+	r := &reader{title: a.works.title}
+	if meta := a.parser.IDTData[workID]; meta != nil {
+		r.title += ", " + meta.Title
+	}
+	spans := tlgcore.NewSpanReader(a.parser.IsLatinFile)
+	for _, l := range lines {
+		rich := spans.Spans(l.Beta)
+		if strings.TrimSpace(l.Text) == "" {
+			continue
+		}
+		r.lines = append(r.lines, l)
+		r.toks = append(r.toks, tokens(l.Text))
+		var row []span
+		for _, s := range rich {
+			row = append(row, span{s.Text, tlgcore.SGR(s.Style)})
+		}
+		r.rich = append(r.rich, row)
+	}
+	if len(r.lines) == 0 {
+		return fmt.Errorf("work %s has no text", workID)
+	}
+	r.setLine(0, 0)
+	a.text = r
+	a.mode = modeText
+	a.dict = nil
+	return nil
+}
+
+func (a *app) dictionary() *lexicon.Dictionary {
+	if a.parser != nil && a.parser.IsLatinFile {
+		return a.latin
+	}
+	return a.greek
+}
+
+// setLine moves the cursor to a line and a word of it.
+func (r *reader) setLine(n, word int) {
+	r.cur = max(0, min(n, len(r.lines)-1))
+	r.word = min(max(word, 0), len(r.toks[r.cur])-1)
+}
+
+func (r *reader) moveWord(d int) {
+	w := r.word + d
+	n := r.cur
+	for w < 0 || w >= len(r.toks[n]) {
+		if d > 0 {
+			if n+1 >= len(r.lines) {
+				return
+			}
+			n, w = n+1, 0
+		} else {
+			if n == 0 {
+				return
+			}
+			n = n - 1
+			w = len(r.toks[n]) - 1
+		}
+		if len(r.toks[n]) == 0 {
+			w = -1
+			if d > 0 {
+				w = len(r.toks[n])
+			}
+		}
+	}
+	r.cur, r.word = n, w
+}
+
+func (r *reader) currentWord() string {
+	if r.word < 0 {
+		return ""
+	}
+	t := r.toks[r.cur][r.word]
+	return strings.TrimRight(r.lines[r.cur].Text[t[0]:t[1]], "’'")
+}
+
+// jump moves to the first line at or after a citation.
+func (a *app) jump(cit string) {
+	r := a.text
+	for i, l := range r.lines {
+		if tlgcore.CompareCitation(l.Citation, cit) >= 0 {
+			r.setLine(i, 0)
+			r.top = max(0, i-3)
+			return
+		}
+	}
+	a.status = "no citation " + cit
+}
+
+// search moves to the next (dir 1) or previous (dir -1) line with a
+// word matching the query, ignoring accents and breathings.
+func (a *app) search(dir int) {
+	r := a.text
+	if r.query == "" {
+		return
+	}
+	n := len(r.lines)
+	for step := 1; step <= n; step++ {
+		i := ((r.cur+dir*step)%n + n) % n
+		for w, t := range r.toks[i] {
+			if tlgcore.QueryKey(r.lines[i].Text[t[0]:t[1]], true) == r.query {
+				if (dir > 0 && i <= r.cur) || (dir < 0 && i >= r.cur) {
+					a.status = "search wrapped"
+				}
+				r.cur, r.word = i, w
+				return
+			}
+		}
+	}
+	a.status = "not found"
+}
+
+func (a *app) ask(label string, fn func(string)) {
+	a.prompt, a.input, a.onInput = label, "", fn
+}
+
+func (a *app) run() {
+	a.draw()
+	for k := range a.term.keys {
+		a.status = ""
+		if k == "ctrl-c" {
+			return
+		}
+		if a.prompt != "" {
+			a.promptKey(k)
+		} else {
+			switch a.mode {
+			case modeAuthors:
+				a.listKey(a.authors, k)
+			case modeWorks:
+				a.listKey(a.works, k)
+			case modeText:
+				a.textKey(k)
+			}
+		}
+		if a.quitting {
+			return
+		}
+		a.draw()
+	}
+}
+
+func (a *app) promptKey(k string) {
+	switch k {
+	case "enter":
+		fn, input := a.onInput, a.input
+		a.prompt = ""
+		fn(input)
+	case "esc":
+		a.prompt = ""
+	case "backspace":
+		if r := []rune(a.input); len(r) > 0 {
+			a.input = string(r[:len(r)-1])
+		}
+	default:
+		if utf8.RuneCountInString(k) == 1 {
+			a.input += k
+		}
+	}
+}
+
+func (a *app) back() {
+	switch a.mode {
+	case modeText:
+		a.mode = modeWorks
+		a.dict = nil
+	case modeWorks:
+		if a.authors == nil {
+			a.quitting = true
+			return
+		}
+		a.mode = modeAuthors
+	default:
+		a.quitting = true
+	}
+}
+
+func (a *app) listKey(l *list, k string) {
+	rows, _ := a.term.size()
+	page := rows - 2
+	switch k {
+	case "j", "down":
+		l.sel++
+	case "k", "up":
+		l.sel--
+	case " ", "pgdn":
+		l.sel += page
+	case "b", "pgup":
+		l.sel -= page
+	case "g", "home":
+		l.sel = 0
+	case "G", "end":
+		l.sel = len(l.items) - 1
+	case "/":
+		a.ask("filter: ", l.setFilter)
+	case "enter":
+		if len(l.items) == 0 {
+			return
+		}
+		id := l.items[l.sel].id
+		var err error
+		if a.mode == modeAuthors {
+			err = a.openWorks(id)
+		} else {
+			err = a.openText(id)
+		}
+		if err != nil {
+			a.status = err.Error()
+		}
+	case "q", "esc", "backspace", "h", "left":
+		a.back()
+	case "Q":
+		a.quitting = true
+	}
+	l.sel = max(0, min(l.sel, len(l.items)-1))
+}
+
+func (a *app) textKey(k string) {
+	r := a.text
+	rows, _ := a.term.size()
+	textRows, dictRows := a.panes(rows)
+
+	if a.dictFocus && a.dict != nil {
+		switch k {
+		case "j", "down":
+			a.dictTop++
+		case "k", "up":
+			a.dictTop--
+		case " ", "pgdn":
+			a.dictTop += dictRows
+		case "b", "pgup":
+			a.dictTop -= dictRows
+		case "tab":
+			a.dictFocus = false
+		case "x", "esc", "q":
+			a.dict, a.dictFocus = nil, false
+		}
+		a.dictTop = max(0, min(a.dictTop, len(a.dict)-dictRows))
+		return
+	}
+
+	switch k {
+	case "j", "down":
+		r.setLine(r.cur+1, r.word)
+	case "k", "up":
+		r.setLine(r.cur-1, r.word)
+	case "l", "right", "w":
+		r.moveWord(1)
+	case "h", "left", "B":
+		r.moveWord(-1)
+	case " ", "pgdn":
+		r.top += textRows
+		r.setLine(r.cur+textRows, r.word)
+	case "b", "pgup":
+		r.top -= textRows
+		r.setLine(r.cur-textRows, r.word)
+	case "g", "home":
+		r.setLine(0, 0)
+	case "G", "end":
+		r.setLine(len(r.lines)-1, 0)
+	case "enter":
+		if w := r.currentWord(); w != "" {
+			_, cols := a.term.size()
+			a.dict = wrap(lookup(a.dictionary(), w), cols)
+			a.dictTop = 0
+		}
+	case "tab":
+		if a.dict != nil {
+			a.dictFocus = true
+		}
+	case "x":
+		a.dict = nil
+	case ":":
+		a.ask("citation: ", a.jump)
+	case "/":
+		a.ask("search: ", func(s string) {
+			r.query = tlgcore.QueryKey(s, true)
+			a.search(1)
+		})
+	case "n":
+		a.search(1)
+	case "N":
+		a.search(-1)
+	case "?":
+		_, cols := a.term.size()
+		a.dict = wrap(strings.Split(help, "\n"), cols)
+		a.dictTop = 0
+	case "q", "esc", "backspace":
+		a.back()
+	case "Q":
+		a.quitting = true
+	}
+}
+
+const help = `j k, arrows     previous/next line      h l      previous/next word
+space b         next/previous page      g G      start/end of work
+enter           look up word            tab      scroll dictionary pane
+:               jump to citation        x        close dictionary pane
+/ n N           search, next, previous  q        back (Q quits)`
+
+// panes returns the heights of the text and dictionary panes.
+func (a *app) panes(rows int) (text, dict int) {
+	body := rows - 1
+	if a.mode != modeText || a.dict == nil {
+		return body, 0
+	}
+	text = body * 3 / 5
+	return text, body - text - 1
+}
+
+func (a *app) draw() {
+	t := a.term
+	rows, cols := t.size()
+	textRows, dictRows := a.panes(rows)
+
+	var title, pos string
+	switch a.mode {
+	case modeAuthors, modeWorks:
+		l := a.authors
+		if a.mode == modeWorks {
+			l = a.works
+		}
+		title = l.title
+		if l.filter != "" {
+			title += " [" + l.filter + "]"
+		}
+		pos = fmt.Sprintf("%d/%d", l.sel+1, len(l.items))
+		a.drawList(l, textRows, cols)
+	case modeText:
+		r := a.text
+		title = r.title
+		pos = r.lines[r.cur].Citation
+		a.drawText(textRows, cols)
+		if a.dict != nil {
+			attr := ""
+			if a.dictFocus {
+				attr = "1"
+			}
+			t.row(textRows, cols, span{strings.Repeat("─", cols), attr})
+			for i := range dictRows {
+				line := ""
+				if n := a.dictTop + i; n < len(a.dict) {
+					line = a.dict[n]
+				}
+				t.row(textRows+1+i, cols, span{line, ""})
+			}
+		}
+	}
+
+	status := title + "  " + pos
+	if a.status != "" {
+		status += "  — " + a.status
+	}
+	if a.prompt != "" {
+		status = a.prompt + a.input + "_"
+	} else if n := utf8.RuneCountInString(status); n+6 < cols {
+		status += strings.Repeat(" ", cols-n-6) + "?:help"
+	}
+	t.row(rows-1, cols, span{status, "7"})
+	t.flush()
+}
+
+func (a *app) drawList(l *list, height, cols int) {
+	if l.sel < l.top {
+		l.top = l.sel
+	}
+	if l.sel >= l.top+height {
+		l.top = l.sel - height + 1
+	}
+	for i := range height {
+		n := l.top + i
+		switch {
+		case n >= len(l.items):
+			a.term.row(i, cols)
+		case n == l.sel:
+			a.term.row(i, cols, span{l.items[n].label, "7"})
+		default:
+			a.term.row(i, cols, span{l.items[n].label, ""})
+		}
+	}
+}
+
+func (a *app) drawText(height, cols int) {
+	r := a.text
+	if r.cur < r.top {
+		r.top = r.cur
+	}
+	if r.cur >= r.top+height {
+		r.top = r.cur - height + 1
+	}
+	r.top = max(0, min(r.top, len(r.lines)-1))
+
+	for i := range height {
+		n := r.top + i
+		if n >= len(r.lines) {
+			a.term.row(i, cols)
+			continue
+		}
+		l := r.lines[n]
+		cit := span{fmt.Sprintf("%-10s ", l.Citation), ""}
+		if n != r.cur || r.word < 0 {
+			a.term.row(i, cols, append([]span{cit}, r.rich[n]...)...)
+			continue
+		}
+		cit.attr = "1"
+		tok := r.toks[n][r.word]
+		a.term.row(i, cols, cit,
+			span{l.Text[:tok[0]], ""}, span{l.Text[tok[0]:tok[1]], "7"}, span{l.Text[tok[1]:], ""})
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// terminal puts the controlling terminal into raw mode with stty(1)
+// and draws with ANSI escape sequences on the alternate screen.
+type terminal struct {
+	saved string
+	out   *bufio.Writer
+	keys  chan string
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+func openTerminal() (*terminal, error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("stty: %v (not a terminal?)", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+
+	t := &terminal{saved: saved, out: bufio.NewWriterSize(os.Stdout, 64*1024), keys: make(chan string, 16)}
+	t.out.WriteString("\x1b[?1049h\x1b[?25l")
+	t.out.Flush()
+	go t.readKeys()
+	return t, nil
+}
+
+func (t *terminal) Close() {
+	t.out.WriteString("\x1b[?25h\x1b[?1049l")
+	t.out.Flush()
+	stty(t.saved)
+}
+
+// size returns the number of rows and columns of the terminal.
+func (t *terminal) size() (rows, cols int) {
+	rows, cols = 24, 80
+	s, err := stty("size")
+	if err != nil {
+		return
+	}
+	f := strings.Fields(s)
+	if len(f) == 2 {
+		r, err1 := strconv.Atoi(f[0])
+		c, err2 := strconv.Atoi(f[1])
+		if err1 == nil && err2 == nil && r > 2 && c > 10 {
+			rows, cols = r, c
+		}
+	}
+	return
+}
+
+var escKeys = map[string]string{
+	"[A": "up", "[B": "down", "[C": "right", "[D": "left",
+	"OA": "up", "OB": "down", "OC": "right", "OD": "left",
+	"[5~": "pgup", "[6~": "pgdn",
+	"[H": "home", "[F": "end", "[1~": "home", "[4~": "end",
+	"OH": "home", "OF": "end",
+}
+
+// readKeys sends each key read from stdin as a name ("up", "enter")
+// or as the typed character.
+func (t *terminal) readKeys() {
+	buf := make([]byte, 256)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			close(t.keys)
+			return
+		}
+		b := buf[:n]
+		for len(b) > 0 {
+			switch c := b[0]; {
+			case c == 0x1b && len(b) > 2 && (b[1] == '[' || b[1] == 'O'):
+				i := 2
+				for i < len(b) && (b[i] < 0x40 || b[i] > 0x7e) {
+					i++
+				}
+				if i < len(b) {
+					i++
+				}
+				if k, ok := escKeys[string(b[1:i])]; ok {
+					t.keys <- k
+				}
+				b = b[i:]
+				continue
+			case c == 0x1b:
+				t.keys <- "esc"
+			case c == '\r' || c == '\n':
+				t.keys <- "enter"
+			case c == 0x7f || c == 0x08:
+				t.keys <- "backspace"
+			case c == '\t':
+				t.keys <- "tab"
+			case c == 0x03:
+				t.keys <- "ctrl-c"
+			case c == 0x0c:
+				t.keys <- "ctrl-l"
+			case c < 0x20:
+				// ignore other control characters
+			default:
+				r, size := utf8.DecodeRune(b)
+				t.keys <- string(r)
+				b = b[size:]
+				continue
+			}
+			b = b[1:]
+		}
+	}
+}
+
+// span is a piece of a screen row with an SGR attribute ("" for plain,
+// "7" for reverse video, "1" for bold).
+type span struct {
+	text string
+	attr string
+}
+
+// row draws spans on a screen row, clipped to cols.
+func (t *terminal) row(y, cols int, spans ...span) {
+	fmt.Fprintf(t.out, "\x1b[%d;1H", y+1)
+	w := 0
+	for _, s := range spans {
+		if w >= cols {
+			break
+		}
+		text := s.text
+		if n := utf8.RuneCountInString(text); w+n > cols {
+			text = string([]rune(text)[:cols-w])
+		}
+		w += utf8.RuneCountInString(text)
+		if s.attr != "" {
+			fmt.Fprintf(t.out, "\x1b[%sm%s\x1b[0m", s.attr, text)
+		} else {
+			t.out.WriteString(text)
+		}
+	}
+	t.out.WriteString("\x1b[K")
+}
+
+func (t *terminal) flush() {
+	t.out.Flush()
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"tlgread/pkg/corpus"
+	"tlgread/pkg/morph"
+	"tlgread/pkg/tlgcore"
+)
+
+func main() {
+	fPath := flag.String("f", "", "TLG .txt file(s), comma separated")
+	wID := flag.String("w", "", "Work ID (with a single -f)")
+	dPath := flag.String("d", "", "corpus directory: count every file")
+	analPath := flag.String("a", "greek-analyses.txt", "analyses txt file, to count lemmata (\"\": forms only)")
+	idtPath := flag.String("idt", "greek-analyses.idt", "idt file of -a")
+	top := flag.Int("n", 50, "number of rows per table (0: all)")
+	hapax := flag.Bool("hapax", false, "list hapax legomena")
+	format := flag.String("fmt", "text", "output format: text or csv")
+	workers := flag.Int("j", 0, "number of files scanned in parallel (0: all CPUs)")
+	century := flag.String("century", "", "only works of these centuries, e.g. -5..-4 (negative: B.C.)")
+	genre := flag.String("genre", "", "only works of this genre, e.g. Tragoedia")
+	region := flag.String("region", "", "only authors of this region, e.g. Attica")
+	flag.Parse()
+
+	var files []string
+	switch {
+	case *fPath != "":
+		files = strings.Split(*fPath, ",")
+	case *dPath != "":
+		var err error
+		files, err = corpus.Files(*dPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+	default:
+		log.Fatal("Usage: ./wordfreq -f tlg0012.txt [-w 1] | -f tlg0012.txt,tlg0059.txt | -d dir [-a greek-analyses.txt | -a \"\"]")
+	}
+
+	filter, err := corpus.NewFilter(filepath.Dir(files[0]), *century, *genre, *region)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var freq *corpus.Freq
+	if *wID != "" {
+		if len(files) != 1 {
+			log.Fatal("-w needs a single -f file")
+		}
+		id := tlgcore.NormalizeID(*wID)
+		if !filter.Work(files[0], id) {
+			log.Fatalf("%s work %s is excluded by -century, -genre or -region", files[0], id)
+		}
+		freq, err = corpus.FreqFile(files[0], id)
+	} else {
+		freq, err = corpus.FreqFilesFilter(files, *workers, filter)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	latin := tlgcore.IsLatinName(files[0])
+	if latin {
+		set := make(map[string]bool)
+		flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		for name, file := range map[string]string{"a": "latin-analyses.txt", "idt": "latin-analyses.idt"} {
+			if !set[name] {
+				flag.Set(name, file)
+			}
+		}
+	}
+	display := func(beta string) string {
+		if latin {
+			return beta
+		}
+		return tlgcore.ToGreek(beta)
+	}
+
+	var out table
+	if *format == "csv" {
+		out = &csvTable{w: csv.NewWriter(os.Stdout)}
+	} else {
+		out = &textTable{}
+	}
+
+	out.summary(freq)
+	out.counts("Forms", "Form", corpus.SortCounts(freq.Forms), freq, *top, display)
+
+	if *analPath != "" {
+		analyses, err := morph.OpenAnalyses(*analPath, *idtPath)
+		if err != nil {
+			log.Printf("%v: counting forms only", err)
+		} else {
+			lemmata, ambiguous, unknown := countLemmata(freq, analyses)
+			out.counts("Lemmata", "Lemma", corpus.SortCounts(lemmata), freq, *top, display)
+			out.note(fmt.Sprintf("%d tokens with more than one possible lemma (counted for each), %d tokens not analysed", ambiguous, unknown))
+		}
+	}
+
+	if *hapax {
+		out.hapax(freq.Hapax(), display)
+	}
+	out.flush()
+}
+
+// countLemmata groups form counts by lemma. A form that may belong to
+// several lemmata counts for each of them.
+func countLemmata(freq *corpus.Freq, analyses *morph.Analyses) (lemmata map[string]int, ambiguous, unknown int) {
+	lemmata = make(map[string]int)
+	for form, n := range freq.Forms {
+		ls := analyses.Lemmata(form)
+		switch {
+		case len(ls) == 0:
+			unknown += n
+		case len(ls) > 1:
+			ambiguous += n
+		}
+		for _, l := range ls {
+			lemmata[l] += n
+		}
+	}
+	return lemmata, ambiguous, unknown
+}
+
+type table interface {
+	summary(f *corpus.Freq)
+	counts(title, column string, counts []corpus.Count, freq *corpus.Freq, top int, display func(string) string)
+	hapax(forms []string, display func(string) string)
+	note(s string)
+	flush()
+}
+
+type textTable struct{}
+
+func (textTable) summary(f *corpus.Freq) {
+	fmt.Printf("Tokens: %d | Types: %d | Type/token ratio: %.4f | Hapax legomena: %d\n",
+		f.Tokens, f.Types(), f.TypeTokenRatio(), len(f.Hapax()))
+}
+
+func (textTable) counts(title, column string, counts []corpus.Count, freq *corpus.Freq, top int, display func(string) string) {
+	fmt.Println("----------------------------------------")
+	fmt.Println(title)
+	fmt.Println("----------------------------------------")
+	fmt.Printf("%6s %8s %9s  %s\n", "Rank", "Count", "Freq(%)", column)
+	for i, c := range counts {
+		if top > 0 && i >= top {
+			break
+		}
+		fmt.Printf("%6d %8d %9.3f  %s\n", i+1, c.N, 100*freq.Share(c.N), display(c.Key))
+	}
+}
+
+func (textTable) hapax(forms []string, display func(string) string) {
+	fmt.Println("----------------------------------------")
+	fmt.Println("Hapax legomena")
+	fmt.Println("----------------------------------------")
+	for _, f := range forms {
+		fmt.Println(display(f))
+	}
+}
+
+func (textTable) note(s string) {
+	fmt.Println(s)
+}
+
+func (textTable) flush() {}
+
+type csvTable struct {
+	w *csv.Writer
+}
+
+func (t *csvTable) summary(f *corpus.Freq) {
+	t.w.Write([]string{"table", "rank", "count", "freq", "form"})
+	t.w.Write([]string{"tokens", "", strconv.Itoa(f.Tokens), "", ""})
+	t.w.Write([]string{"types", "", strconv.Itoa(f.Types()), strconv.FormatFloat(f.TypeTokenRatio(), 'f', 4, 64), ""})
+}
+
+func (t *csvTable) counts(title, column string, counts []corpus.Count, freq *corpus.Freq, top int, display func(string) string) {
+	for i, c := range counts {
+		if top > 0 && i >= top {
+			break
+		}
+		share := strconv.FormatFloat(freq.Share(c.N), 'f', 6, 64)
+		t.w.Write([]string{strings.ToLower(title), strconv.Itoa(i + 1), strconv.Itoa(c.N), share, display(c.Key)})
+	}
+}
+
+func (t *csvTable) hapax(forms []string, display func(string) string) {
+	for _, f := range forms {
+		t.w.Write([]string{"hapax", "", "1", "", display(f)})
+	}
+}
+
+func (t *csvTable) note(string) {}
+
+func (t *csvTable) flush() {
+	t.w.Flush()
+}
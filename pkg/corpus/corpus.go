@@ -0,0 +1,169 @@
+// Package corpus runs operations over every text file of a TLG or PHI
+// corpus directory.
+package corpus
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"tlgread/pkg/tlgcore"
+)
+
+// Files returns the tlg*.txt and lat*.txt files in dir, sorted by name.
+func Files(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || filepath.Ext(name) != ".txt" {
+			continue
+		}
+		if strings.HasPrefix(name, "tlg") || strings.HasPrefix(name, "lat") {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Scan calls fn for each file using up to workers goroutines (all CPUs
+// if workers <= 0). It returns the first error, if any.
+func Scan(files []string, workers int, fn func(path string) error) error {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				if err := fn(path); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, f := range files {
+		jobs <- f
+	}
+	close(jobs)
+	wg.Wait()
+	return firstErr
+}
+
+// Authors gives the author names of a corpus from its authtab.dir,
+// which is read on first use.
+type Authors struct {
+	path  string
+	once  sync.Once
+	table *tlgcore.AuthorTable
+}
+
+func NewAuthors(dir string) *Authors {
+	return &Authors{path: filepath.Join(dir, "authtab.dir")}
+}
+
+// Table returns the author table, nil if authtab.dir cannot be read.
+func (a *Authors) Table() *tlgcore.AuthorTable {
+	a.once.Do(func() {
+		a.table, _ = tlgcore.LoadAuthorTable(a.path)
+	})
+	return a.table
+}
+
+// Name returns the author of a corpus file such as tlg0012.txt.
+func (a *Authors) Name(file string) string {
+	base := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	t := a.Table()
+	if t == nil {
+		return "Unknown"
+	}
+	return t.Name(base)
+}
+
+// Hit is a line of a corpus file that contains the searched word.
+type Hit struct {
+	Path  string
+	Title string
+	URN   *tlgcore.URN
+	Line  *tlgcore.Line
+	Word  string // the matching word form as it appears in the text
+}
+
+// Search looks for a word in every file. The key must come from
+// tlgcore.QueryKey with the same strict setting. Hits are returned in
+// file order.
+func Search(files []string, key string, strict bool, workers int) ([]Hit, error) {
+	return SearchFunc(files, workers, func(w string) bool {
+		return tlgcore.WordKey(w, strict) == key
+	})
+}
+
+// SearchFunc returns, in file order, the lines of all files with a
+// word accepted by match. match must be safe for concurrent use.
+func SearchFunc(files []string, workers int, match func(word string) bool) ([]Hit, error) {
+	results := make([][]Hit, len(files))
+	index := make(map[string]int, len(files))
+	for i, f := range files {
+		index[f] = i
+	}
+
+	err := Scan(files, workers, func(path string) error {
+		hits, err := SearchFile(path, match)
+		results[index[path]] = hits
+		return err
+	})
+
+	var hits []Hit
+	for _, r := range results {
+		hits = append(hits, r...)
+	}
+	return hits, err
+}
+
+// SearchFile returns the lines of a file with a word accepted by match.
+func SearchFile(path string, match func(word string) bool) ([]Hit, error) {
+	p, err := tlgcore.OpenText(path)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Close()
+
+	var hits []Hit
+	for l, err := range p.Lines() {
+		if err != nil {
+			return hits, err
+		}
+		for _, w := range tlgcore.Words(l.Beta) {
+			if !match(w) {
+				continue
+			}
+			h := Hit{Path: path, Line: l, Word: w, URN: p.URN(l)}
+			if meta := p.IDTData[l.WorkID]; meta != nil {
+				h.Title = meta.Title
+			}
+			hits = append(hits, h)
+			break
+		}
+	}
+	return hits, nil
+}
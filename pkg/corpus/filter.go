@@ -0,0 +1,306 @@
+package corpus
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"tlgread/pkg/tlgcore"
+)
+
+// Filter restricts corpus operations to works of given centuries,
+// genres or regions, as recorded in the TLG canon (see tlgcore.Canon)
+// and, for genres, in the epithets of authtab.dir. A nil *Filter
+// accepts everything.
+type Filter struct {
+	Century  bool // whether From and To are set
+	From, To int  // centuries, negative before Christ: -5..-4
+	Genre    string
+	Region   string
+
+	canon   *tlgcore.Canon
+	authors *Authors
+}
+
+// NewFilter returns the filter for a corpus directory, or nil if no
+// criterion is given. centuries is a century ("-5") or a range
+// ("-5..-4").
+func NewFilter(dir, centuries, genre, region string) (*Filter, error) {
+	if centuries == "" && genre == "" && region == "" {
+		return nil, nil
+	}
+	f := &Filter{Genre: genre, Region: region, authors: NewAuthors(dir)}
+	if centuries != "" {
+		var err error
+		if f.From, f.To, err = ParseCenturies(centuries); err != nil {
+			return nil, err
+		}
+		f.Century = true
+	}
+	f.canon, _ = tlgcore.LoadCanon(dir)
+	if f.canon == nil && (f.Century || region != "") {
+		return nil, fmt.Errorf("no TLG canon (doccan1.txt, doccan2.txt) in %s", dir)
+	}
+	return f, nil
+}
+
+// ParseCenturies parses "-5..-4" or "-5" (the fifth century B.C.).
+func ParseCenturies(s string) (from, to int, err error) {
+	a, b, isRange := strings.Cut(s, "..")
+	if from, err = strconv.Atoi(strings.TrimSpace(a)); err != nil || from == 0 {
+		return 0, 0, fmt.Errorf("bad century %q", a)
+	}
+	to = from
+	if isRange {
+		if to, err = strconv.Atoi(strings.TrimSpace(b)); err != nil || to == 0 {
+			return 0, 0, fmt.Errorf("bad century %q", b)
+		}
+	}
+	if from > to {
+		from, to = to, from
+	}
+	return from, to, nil
+}
+
+var (
+	dateNumRe = regexp.MustCompile(`(?i)\b(\d+|[IVX]+)\b|B\.\s?C\.|A\.\s?D\.|a\.\s?Chr\.|p\.\s?Chr\.`)
+	romanVals = map[byte]int{'I': 1, 'V': 5, 'X': 10}
+)
+
+// CanonCenturies reads the centuries of a canon date such as "5 B.C.",
+// "5-4 B.C.", "A.D. 2", "4 B.C.-A.D. 1" or "saec. V a. Chr.". A number
+// takes the era written after it, else the one before it, else A.D.
+// Numbers above 30 are years.
+func CanonCenturies(date string) (from, to int, ok bool) {
+	type num struct {
+		n  int
+		bc int // 1 B.C., -1 A.D., 0 not yet known
+	}
+	var nums []num
+	lastEra := 0
+	for _, m := range dateNumRe.FindAllString(date, -1) {
+		era := 0
+		switch strings.ToUpper(strings.ReplaceAll(m, " ", "")) {
+		case "B.C.", "A.CHR.":
+			era = 1
+		case "A.D.", "P.CHR.":
+			era = -1
+		}
+		if era != 0 {
+			for i := range nums {
+				if nums[i].bc == 0 {
+					nums[i].bc = era
+				}
+			}
+			lastEra = era
+			continue
+		}
+		n, err := strconv.Atoi(m)
+		if err != nil {
+			n = roman(strings.ToUpper(m))
+		}
+		if n > 30 {
+			n = (n-1)/100 + 1
+		}
+		if n > 0 {
+			nums = append(nums, num{n, 0})
+		}
+	}
+	if len(nums) == 0 {
+		return 0, 0, false
+	}
+
+	from, to = 1<<30, -1<<30
+	for _, x := range nums {
+		c := x.n
+		bc := x.bc
+		if bc == 0 {
+			bc = lastEra
+		}
+		if bc == 1 {
+			c = -c
+		}
+		from, to = min(from, c), max(to, c)
+	}
+	return from, to, true
+}
+
+func roman(s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		v := romanVals[s[i]]
+		if i+1 < len(s) && romanVals[s[i+1]] > v {
+			n -= v
+		} else {
+			n += v
+		}
+	}
+	return n
+}
+
+// matchTerm reports whether a genre or region query matches a canon or
+// authtab field, allowing for abbreviations: "Tragoedia" matches
+// "Trag." and "trag" matches "Tragoedia".
+func matchTerm(query, field string) bool {
+	q := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(query), "."))
+	if q == "" {
+		return false
+	}
+	for _, w := range strings.FieldsFunc(strings.ToLower(field), func(r rune) bool {
+		return r == ' ' || r == ',' || r == ';' || r == '/' || r == '(' || r == ')'
+	}) {
+		w = strings.TrimSuffix(w, ".")
+		if len(w) < 3 {
+			continue
+		}
+		if strings.HasPrefix(w, q) || strings.HasPrefix(q, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func authorID(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// author checks the criteria that do not depend on the work.
+func (f *Filter) author(id string) bool {
+	if f.Region == "" {
+		return true
+	}
+	a := f.canon.Author(id)
+	return a != nil && matchTerm(f.Region, a.Region)
+}
+
+// Work reports whether a work of a corpus file passes the filter.
+func (f *Filter) Work(path, workID string) bool {
+	if f == nil {
+		return true
+	}
+	id := authorID(path)
+	if !f.author(id) {
+		return false
+	}
+
+	var a *tlgcore.CanonAuthor
+	var w *tlgcore.CanonWork
+	if f.canon != nil {
+		a = f.canon.Author(id)
+		w = f.canon.Work(id, workID)
+	}
+
+	if f.Century {
+		date := ""
+		if w != nil && w.Date != "" {
+			date = w.Date
+		} else if a != nil {
+			date = a.Date
+		}
+		from, to, ok := CanonCenturies(date)
+		if !ok || to < f.From || from > f.To {
+			return false
+		}
+	}
+
+	if f.Genre != "" {
+		var fields []string
+		if w != nil {
+			fields = append(fields, w.Genre)
+		}
+		if a != nil {
+			fields = append(fields, a.Genre, a.Epithet)
+		}
+		if t := f.authors.Table(); t != nil {
+			if r := t.Lookup(id); r != nil {
+				fields = append(fields, r.Epithet)
+			}
+		}
+		found := false
+		for _, field := range fields {
+			if matchTerm(f.Genre, field) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// WorkFunc returns Work with its decisions remembered per file and work:
+// Work looks up the canon on each call. The function is not safe for
+// concurrent use.
+func (f *Filter) WorkFunc() func(path, workID string) bool {
+	type key struct{ path, work string }
+	seen := make(map[key]bool)
+	return func(path, workID string) bool {
+		k := key{path, workID}
+		ok, found := seen[k]
+		if !found {
+			ok = f.Work(path, workID)
+			seen[k] = ok
+		}
+		return ok
+	}
+}
+
+// Files returns the files that have at least one work passing the
+// filter.
+func (f *Filter) Files(files []string) []string {
+	if f == nil {
+		return files
+	}
+	var kept []string
+	for _, path := range files {
+		if !f.author(authorID(path)) {
+			continue
+		}
+		meta, err := tlgcore.ReadIDT(strings.TrimSuffix(path, filepath.Ext(path)) + ".idt")
+		if err != nil {
+			continue
+		}
+		for id := range meta {
+			if f.Work(path, id) {
+				kept = append(kept, path)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// Hits drops the hits in works that do not pass the filter.
+func (f *Filter) Hits(hits []Hit) []Hit {
+	if f == nil {
+		return hits
+	}
+	work := f.WorkFunc()
+	var kept []Hit
+	for _, h := range hits {
+		if work(h.Path, h.Line.WorkID) {
+			kept = append(kept, h)
+		}
+	}
+	return kept
+}
+
+// KWIC drops the concordance entries in works that do not pass the
+// filter.
+func (f *Filter) KWIC(entries []KWIC) []KWIC {
+	if f == nil {
+		return entries
+	}
+	work := f.WorkFunc()
+	var kept []KWIC
+	for _, e := range entries {
+		if work(e.Path, e.WorkID) {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
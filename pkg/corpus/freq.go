@@ -0,0 +1,159 @@
+package corpus
+
+import (
+	"sort"
+
+	"tlgread/pkg/tlgcore"
+)
+
+// Freq counts the word forms of a text.
+type Freq struct {
+	Tokens int
+	Forms  map[string]int // tlgcore.WordKey -> count
+}
+
+func NewFreq() *Freq {
+	return &Freq{Forms: make(map[string]int)}
+}
+
+func (f *Freq) AddLines(lines []*tlgcore.Line) {
+	for _, l := range lines {
+		for _, w := range tlgcore.Words(l.Beta) {
+			key := tlgcore.WordKey(w, false)
+			if key == "" {
+				continue
+			}
+			f.Forms[key]++
+			f.Tokens++
+		}
+	}
+}
+
+// Merge adds the counts of another table.
+func (f *Freq) Merge(o *Freq) {
+	f.Tokens += o.Tokens
+	for k, n := range o.Forms {
+		f.Forms[k] += n
+	}
+}
+
+func (f *Freq) Types() int {
+	return len(f.Forms)
+}
+
+func (f *Freq) TypeTokenRatio() float64 {
+	if f.Tokens == 0 {
+		return 0
+	}
+	return float64(len(f.Forms)) / float64(f.Tokens)
+}
+
+// Share returns n as a fraction of the tokens, or 0 if there are none.
+func (f *Freq) Share(n int) float64 {
+	if f.Tokens == 0 {
+		return 0
+	}
+	return float64(n) / float64(f.Tokens)
+}
+
+// Hapax returns the forms that occur exactly once, sorted.
+func (f *Freq) Hapax() []string {
+	var h []string
+	for k, n := range f.Forms {
+		if n == 1 {
+			h = append(h, k)
+		}
+	}
+	sort.Strings(h)
+	return h
+}
+
+type Count struct {
+	Key string
+	N   int
+}
+
+// SortCounts orders a count table by descending count, then by key.
+func SortCounts(m map[string]int) []Count {
+	counts := make([]Count, 0, len(m))
+	for k, n := range m {
+		counts = append(counts, Count{k, n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].N != counts[j].N {
+			return counts[i].N > counts[j].N
+		}
+		return counts[i].Key < counts[j].Key
+	})
+	return counts
+}
+
+// FreqFile counts the words of a file, or of one work if workID is set.
+func FreqFile(path, workID string) (*Freq, error) {
+	if workID == "" {
+		return freqWorks(path, nil)
+	}
+	p, err := tlgcore.OpenText(path)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Close()
+
+	lines, err := p.WorkLines(workID)
+	if err != nil {
+		return nil, err
+	}
+	f := NewFreq()
+	f.AddLines(lines)
+	return f, nil
+}
+
+// freqWorks counts the words of the works of a file that pass filter.
+func freqWorks(path string, filter *Filter) (*Freq, error) {
+	p, err := tlgcore.OpenText(path)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Close()
+
+	work := filter.WorkFunc()
+	f := NewFreq()
+	for l, err := range p.Lines() {
+		if err != nil {
+			return f, err
+		}
+		if work(path, l.WorkID) {
+			f.AddLines([]*tlgcore.Line{l})
+		}
+	}
+	return f, nil
+}
+
+// FreqFiles counts the words of all files.
+func FreqFiles(files []string, workers int) (*Freq, error) {
+	return FreqFilesFilter(files, workers, nil)
+}
+
+// FreqFilesFilter counts the words of the works that pass filter.
+func FreqFilesFilter(files []string, workers int, filter *Filter) (*Freq, error) {
+	files = filter.Files(files)
+	results := make([]*Freq, len(files))
+	index := make(map[string]int, len(files))
+	for i, f := range files {
+		index[f] = i
+	}
+
+	err := Scan(files, workers, func(path string) error {
+		f, err := freqWorks(path, filter)
+		results[index[path]] = f
+		return err
+	})
+
+	total := NewFreq()
+	for _, f := range results {
+		if f != nil {
+			total.Merge(f)
+		}
+	}
+	return total, err
+}
@@ -0,0 +1,263 @@
+package corpus
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"tlgread/pkg/tlgcore"
+)
+
+// KWIC is a keyword-in-context entry of a concordance.
+type KWIC struct {
+	Path     string
+	Title    string
+	WorkID   string
+	Citation string
+	URN      *tlgcore.URN
+	Left     string
+	Hit      string
+	Right    string
+
+	leftKey  string // neighbouring words for sorting, nearest first
+	rightKey string
+}
+
+// chunk is a space-separated piece of Beta Code with its display text.
+type chunk struct {
+	text     string
+	keys     []string // strict word keys, for sorting
+	words    []string
+	citation string
+	line     *tlgcore.Line
+}
+
+const sortContext = 5 // words used as sort key on each side
+
+// chunks splits the lines of one work into words. A word hyphenated at
+// the end of a line is joined with its continuation on the next line.
+// The pieces are converted in order by one SpanReader, so that a change
+// of language or an open quotation carries over to the next.
+func chunks(lines []*tlgcore.Line, latin bool) []chunk {
+	var out []chunk
+	pending := ""
+	var pendingLine *tlgcore.Line
+	spans := tlgcore.NewSpanReader(latin)
+
+	add := func(beta string, l *tlgcore.Line) {
+		words := tlgcore.Words(beta)
+		text := strings.TrimSpace(tlgcore.PlainText(spans.Spans(beta)))
+		if text == "" {
+			return
+		}
+		c := chunk{text: text, words: words, citation: l.Citation, line: l}
+		for _, w := range words {
+			c.keys = append(c.keys, tlgcore.WordKey(w, true))
+		}
+		out = append(out, c)
+	}
+
+	for _, l := range lines {
+		fields := strings.Fields(l.Beta)
+		for i, f := range fields {
+			if pending != "" {
+				f = pending + f
+				pending = ""
+				add(f, pendingLine)
+				continue
+			}
+			if i == len(fields)-1 && strings.HasSuffix(f, "-") && len(f) > 1 {
+				pending = strings.TrimSuffix(f, "-")
+				pendingLine = l
+				continue
+			}
+			add(f, l)
+		}
+	}
+	if pending != "" {
+		add(pending+"-", pendingLine)
+	}
+	return out
+}
+
+// Concordance returns KWIC entries for the words of a work's lines
+// accepted by match, with width characters of context on each side.
+func Concordance(path, title string, lines []*tlgcore.Line, width int, match func(word string) bool) []KWIC {
+	cs := chunks(lines, tlgcore.IsLatinName(path))
+
+	var entries []KWIC
+	for i, c := range cs {
+		hit := false
+		for _, w := range c.words {
+			if match(w) {
+				hit = true
+				break
+			}
+		}
+		if !hit {
+			continue
+		}
+
+		e := KWIC{
+			Path:     path,
+			Title:    title,
+			WorkID:   c.line.WorkID,
+			Citation: c.citation,
+			URN:      tlgcore.NewURN(path, c.line.WorkID),
+			Hit:      c.text,
+		}
+		e.URN.From = c.citation
+
+		var left, right []string
+		var leftKeys, rightKeys []string
+		for j, n := i-1, 0; j >= 0 && (n < width || len(leftKeys) < sortContext); j-- {
+			if n < width {
+				left = append([]string{cs[j].text}, left...)
+				n += utf8.RuneCountInString(cs[j].text) + 1
+			}
+			leftKeys = append(leftKeys, reverse(cs[j].keys)...)
+		}
+		for j, n := i+1, 0; j < len(cs) && (n < width || len(rightKeys) < sortContext); j++ {
+			if n < width {
+				right = append(right, cs[j].text)
+				n += utf8.RuneCountInString(cs[j].text) + 1
+			}
+			rightKeys = append(rightKeys, cs[j].keys...)
+		}
+
+		e.Left = lastRunes(strings.Join(left, " "), width)
+		e.Right = firstRunes(strings.Join(right, " "), width)
+		e.leftKey = strings.Join(leftKeys, " ")
+		e.rightKey = strings.Join(rightKeys, " ")
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func reverse(s []string) []string {
+	r := make([]string, len(s))
+	for i, v := range s {
+		r[len(s)-1-i] = v
+	}
+	return r
+}
+
+func lastRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) > n {
+		r = r[len(r)-n:]
+	}
+	return string(r)
+}
+
+func firstRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) > n {
+		r = r[:n]
+	}
+	return string(r)
+}
+
+// ConcordanceFile builds the concordance of every work in a file.
+func ConcordanceFile(path string, width int, match func(word string) bool) ([]KWIC, error) {
+	p, err := tlgcore.OpenText(path)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Close()
+
+	var entries []KWIC
+	var work []*tlgcore.Line
+	flush := func() {
+		if len(work) == 0 {
+			return
+		}
+		title := ""
+		if meta := p.IDTData[work[0].WorkID]; meta != nil {
+			title = meta.Title
+		}
+		entries = append(entries, Concordance(path, title, work, width, match)...)
+		work = nil
+	}
+
+	for l, err := range p.Lines() {
+		if err != nil {
+			return entries, err
+		}
+		if len(work) > 0 && work[0].WorkID != l.WorkID {
+			flush()
+		}
+		work = append(work, l)
+	}
+	flush()
+	return entries, nil
+}
+
+// ConcordanceFunc builds the concordance of all files, in file order.
+func ConcordanceFunc(files []string, workers, width int, match func(word string) bool) ([]KWIC, error) {
+	results := make([][]KWIC, len(files))
+	index := make(map[string]int, len(files))
+	for i, f := range files {
+		index[f] = i
+	}
+
+	err := Scan(files, workers, func(path string) error {
+		entries, err := ConcordanceFile(path, width, match)
+		results[index[path]] = entries
+		return err
+	})
+
+	var entries []KWIC
+	for _, r := range results {
+		entries = append(entries, r...)
+	}
+	return entries, err
+}
+
+// ConcordanceWorks builds the concordance of selected works only, e.g.
+// those an index lookup found. works maps corpus file names (without
+// extension) to work IDs.
+func ConcordanceWorks(dir string, works map[string][]string, width int, match func(word string) bool) ([]KWIC, error) {
+	var files []string
+	for f := range works {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+
+	var entries []KWIC
+	for _, f := range files {
+		path := filepath.Join(dir, f+".txt")
+		p, err := tlgcore.OpenText(path)
+		if err != nil {
+			return entries, err
+		}
+		ids := works[f]
+		sort.Slice(ids, func(i, j int) bool { return tlgcore.CompareCitation(ids[i], ids[j]) < 0 })
+		for _, id := range ids {
+			lines, err := p.WorkLines(id)
+			if err != nil {
+				p.Close()
+				return entries, err
+			}
+			title := ""
+			if meta := p.IDTData[id]; meta != nil {
+				title = meta.Title
+			}
+			entries = append(entries, Concordance(path, title, lines, width, match)...)
+		}
+		p.Close()
+	}
+	return entries, nil
+}
+
+// SortKWIC sorts entries by "left" or "right" context; any other order
+// keeps the text order.
+func SortKWIC(entries []KWIC, by string) {
+	switch by {
+	case "left":
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].leftKey < entries[j].leftKey })
+	case "right":
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].rightKey < entries[j].rightKey })
+	}
+}
@@ -0,0 +1,329 @@
+package export
+
+import (
+	"archive/zip"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fontTypes are the media types of the fonts an EPUB may embed.
+var fontTypes = map[string]string{
+	".ttf":   "font/ttf",
+	".otf":   "font/otf",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+}
+
+// epubItem is a file of the EPUB listed in the package manifest.
+type epubItem struct {
+	id, href, mediaType, props string
+}
+
+// EPUB writes a document as an EPUB 3 book: a title page, then one
+// chapter per top-level citation unit of each work (see
+// Work.Chapters), and a navigation document listing them by their
+// citation labels. If font names a TrueType, OpenType or WOFF file, it
+// is embedded and used for the text; otherwise the reader's fonts are.
+func EPUB(w io.Writer, d *Document, font string) error {
+	z := zip.NewWriter(w)
+
+	// The mimetype file comes first, stored and without extra fields,
+	// so that readers can identify the archive by its first bytes.
+	mimetype := []byte("application/epub+zip")
+	f, err := z.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(mimetype),
+		CompressedSize64:   uint64(len(mimetype)),
+		UncompressedSize64: uint64(len(mimetype)),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(mimetype); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	create := func(name string) (io.Writer, error) {
+		return z.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now})
+	}
+	add := func(name, content string) error {
+		f, err := create(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f, content)
+		return err
+	}
+	if err := add("META-INF/container.xml", epubContainer); err != nil {
+		return err
+	}
+
+	items := []epubItem{
+		{"nav", "nav.xhtml", "application/xhtml+xml", "nav"},
+		{"css", "style.css", "text/css", ""},
+	}
+	fontFamily := ""
+	if font != "" {
+		data, err := os.ReadFile(font)
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(font))
+		mediaType, ok := fontTypes[ext]
+		if !ok {
+			return fmt.Errorf("%s: not a TrueType, OpenType or WOFF font", font)
+		}
+		href := "fonts/text" + ext
+		f, err := create("OEBPS/" + href)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(data); err != nil {
+			return err
+		}
+		items = append(items, epubItem{"font", href, mediaType, ""})
+		fontFamily = "TextFont"
+	}
+	if err := add("OEBPS/style.css", epubStyle(fontFamily, items)); err != nil {
+		return err
+	}
+
+	lang := "grc"
+	if d.Latin {
+		lang = "la"
+	}
+
+	// Title page and chapters, in reading order.
+	spine := []epubItem{{"title", "title.xhtml", "application/xhtml+xml", ""}}
+	if err := add("OEBPS/title.xhtml", epubTitlePage(d, lang)); err != nil {
+		return err
+	}
+	var nav strings.Builder
+	nav.WriteString("<ol>\n")
+	n := 0
+	for _, work := range d.Works {
+		chapters := work.Chapters()
+		if len(chapters) == 0 {
+			continue
+		}
+		if len(d.Works) > 1 {
+			fmt.Fprintf(&nav, "<li><a href=\"ch%03d.xhtml\">%s</a>\n<ol>\n", n+1, xmlEscaper.Replace(work.Meta.Title))
+		}
+		for _, ch := range chapters {
+			n++
+			href := fmt.Sprintf("ch%03d.xhtml", n)
+			if err := add("OEBPS/"+href, epubChapter(ch, lang)); err != nil {
+				return err
+			}
+			spine = append(spine, epubItem{fmt.Sprintf("ch%03d", n), href, "application/xhtml+xml", ""})
+			label := ch.Label
+			if label == "" {
+				label = work.Meta.Title
+			}
+			fmt.Fprintf(&nav, "<li><a href=\"%s\">%s</a></li>\n", href, xmlEscaper.Replace(label))
+		}
+		if len(d.Works) > 1 {
+			nav.WriteString("</ol>\n</li>\n")
+		}
+	}
+	nav.WriteString("</ol>\n")
+	if err := add("OEBPS/nav.xhtml", epubNav(d, lang, nav.String())); err != nil {
+		return err
+	}
+	if err := add("OEBPS/content.opf", epubPackage(d, lang, append(items, spine...), spine)); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+func epubStyle(fontFamily string, items []epubItem) string {
+	var b strings.Builder
+	family := `serif`
+	for _, it := range items {
+		if it.id == "font" {
+			fmt.Fprintf(&b, "@font-face {\n  font-family: %q;\n  src: url(%q);\n}\n", fontFamily, it.href)
+			family = fmt.Sprintf("%q, serif", fontFamily)
+		}
+	}
+	fmt.Fprintf(&b, "body { font-family: %s; }\n", family)
+	b.WriteString(`h1, h2, h3 { text-align: center; }
+p.title { text-align: center; font-size: 1.4em; }
+p.author { text-align: center; font-size: 1.2em; }
+p.bib { text-align: center; font-size: 0.9em; }
+div.l { margin: 0 0 0 3em; text-indent: -1em; position: relative; }
+span.n { position: absolute; left: -3em; width: 2em; text-align: right; font-size: 0.8em; }
+p { text-align: justify; }
+span.cit { font-size: 0.8em; font-weight: bold; }
+`)
+	return b.String()
+}
+
+// epubPage wraps the body of an XHTML content document.
+func epubPage(lang, title, body string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="` + lang + `" lang="` + lang + `">
+<head>
+<meta charset="UTF-8"/>
+<title>` + xmlEscaper.Replace(title) + `</title>
+<link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+` + body + `</body>
+</html>
+`
+}
+
+func epubTitlePage(d *Document, lang string) string {
+	var b strings.Builder
+	b.WriteString("<section epub:type=\"titlepage\">\n")
+	fmt.Fprintf(&b, "<p class=\"author\">%s</p>\n", xmlEscaper.Replace(d.Author))
+	fmt.Fprintf(&b, "<p class=\"title\">%s</p>\n", xmlEscaper.Replace(d.Title()))
+	for _, w := range d.Works {
+		if len(d.Works) > 1 {
+			fmt.Fprintf(&b, "<p class=\"bib\"><i>%s</i></p>\n", xmlEscaper.Replace(w.Meta.Title))
+		}
+		if w.From != "" {
+			fmt.Fprintf(&b, "<p class=\"bib\">%s</p>\n", xmlEscaper.Replace(passageRange(w)))
+		}
+		if w.Meta.Edition != "" {
+			fmt.Fprintf(&b, "<p class=\"bib\">%s</p>\n", xmlEscaper.Replace(w.Meta.Edition))
+		}
+	}
+	b.WriteString("</section>\n")
+	return epubPage(lang, d.Title(), b.String())
+}
+
+// passageRange describes the passage of a work, e.g. "2.100–2.150".
+func passageRange(w *Work) string {
+	if w.To == "" || w.To == w.From {
+		return w.From
+	}
+	return w.From + "–" + w.To
+}
+
+func epubNav(d *Document, lang, list string) string {
+	return epubPage(lang, d.Title(), "<nav epub:type=\"toc\" id=\"toc\">\n<h1>"+
+		xmlEscaper.Replace(d.Title())+"</h1>\n"+list+"</nav>\n")
+}
+
+// epubChapter writes the lines of a chapter. Verse is set line by line
+// with every fifth line numbered; prose is run together into a
+// paragraph per lowest citation unit, marked with its citation.
+func epubChapter(ch *Chapter, lang string) string {
+	w := ch.Work
+	var b strings.Builder
+	b.WriteString("<section epub:type=\"chapter\">\n")
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", xmlEscaper.Replace(w.Meta.Title))
+	if ch.Label != "" {
+		fmt.Fprintf(&b, "<h2>%s</h2>\n", xmlEscaper.Replace(ch.Label))
+	}
+
+	units := w.Units()
+	if len(units) > 0 {
+		units = units[1:]
+	}
+	verse := w.IsVerse()
+	prev := ""
+	inP, hyphen := false, false
+	for _, l := range ch.Lines {
+		var vals []string
+		for _, u := range units {
+			vals = append(vals, Value(l, u))
+		}
+		cit := strings.Join(vals, ".")
+		text := xmlEscaper.Replace(strings.TrimSpace(l.Text))
+
+		if verse {
+			if cit != prev && len(units) > 0 {
+				fmt.Fprintf(&b, "<h3>%s %s</h3>\n", xmlEscaper.Replace(units[len(units)-1].Label), xmlEscaper.Replace(vals[len(vals)-1]))
+			}
+			prev = cit
+			n := Value(l, w.LineLevel())
+			num := ""
+			if i, err := strconv.Atoi(n); err == nil && i%5 == 0 {
+				num = "<span class=\"n\">" + xmlEscaper.Replace(n) + "</span>"
+			}
+			fmt.Fprintf(&b, "<div class=\"l\">%s%s</div>\n", num, text)
+			continue
+		}
+
+		if !inP || cit != prev {
+			if inP {
+				b.WriteString("</p>\n")
+			}
+			b.WriteString("<p>")
+			if cit != "" {
+				fmt.Fprintf(&b, "<span class=\"cit\">%s</span> ", xmlEscaper.Replace(cit))
+			}
+			inP = true
+			prev = cit
+		} else if !hyphen {
+			b.WriteString(" ")
+		}
+		// A word hyphenated at the end of a line is joined.
+		text, hyphen = strings.CutSuffix(text, "-")
+		b.WriteString(text)
+	}
+	if inP {
+		b.WriteString("</p>\n")
+	}
+	b.WriteString("</section>\n")
+	return epubPage(lang, w.Meta.Title, b.String())
+}
+
+// epubPackage writes the package document: the metadata, the manifest
+// of all files and the spine, the content documents in reading order.
+func epubPackage(d *Document, lang string, manifest, spine []epubItem) string {
+	id := d.Works[0].URN.String()
+	if len(d.Works) > 1 {
+		u := d.Works[0].URN
+		id = "urn:cts:" + u.Namespace + ":" + u.TextGroup
+	}
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="id" xml:lang="en">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+`)
+	fmt.Fprintf(&b, "    <dc:identifier id=\"id\">%s</dc:identifier>\n", xmlEscaper.Replace(id))
+	fmt.Fprintf(&b, "    <dc:title>%s</dc:title>\n", xmlEscaper.Replace(d.Title()))
+	fmt.Fprintf(&b, "    <dc:creator>%s</dc:creator>\n", xmlEscaper.Replace(d.Author))
+	fmt.Fprintf(&b, "    <dc:language>%s</dc:language>\n", lang)
+	for _, w := range d.Works {
+		if w.Meta.Edition != "" {
+			fmt.Fprintf(&b, "    <dc:source>%s</dc:source>\n", xmlEscaper.Replace(w.Meta.Edition))
+		}
+	}
+	fmt.Fprintf(&b, "    <meta property=\"dcterms:modified\">%s</meta>\n", time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+	b.WriteString("  </metadata>\n  <manifest>\n")
+	for _, it := range manifest {
+		props := ""
+		if it.props != "" {
+			props = ` properties="` + it.props + `"`
+		}
+		fmt.Fprintf(&b, "    <item id=\"%s\" href=\"%s\" media-type=\"%s\"%s/>\n", it.id, it.href, it.mediaType, props)
+	}
+	b.WriteString("  </manifest>\n  <spine>\n")
+	for _, it := range spine {
+		fmt.Fprintf(&b, "    <itemref idref=\"%s\"/>\n", it.id)
+	}
+	b.WriteString("  </spine>\n</package>\n")
+	return b.String()
+}
@@ -0,0 +1,199 @@
+// Package export writes TLG and PHI works in formats for other tools:
+// TEI XML, EPUB and LaTeX.
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"tlgread/pkg/tlgcore"
+)
+
+// Document is what is exported: one work, a passage of it, or all the
+// works of an author.
+type Document struct {
+	File     string // e.g. "tlg0012.txt"
+	AuthorID string // e.g. "tlg0012"
+	Author   string
+	Epithet  string
+	Latin    bool
+	Works    []*Work
+}
+
+// Work is a work of a Document with the lines to export.
+type Work struct {
+	Meta  *tlgcore.WorkMetadata
+	URN   *tlgcore.URN
+	From  string // first and last citation of a passage, else empty
+	To    string
+	Lines []*tlgcore.Line
+}
+
+// Load reads a work of a corpus file, or all of its works if workID is
+// empty. from and to select a passage as in Parser.ExtractRange. The
+// metadata comes from the IDT file, authtab.dir and, if present, the
+// TLG canon in the same directory.
+func Load(path, workID, from, to string) (*Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dir, base := filepath.Split(path)
+	id := strings.TrimSuffix(base, filepath.Ext(base))
+	d := &Document{File: base, AuthorID: id, Author: id, Latin: tlgcore.IsLatinName(base)}
+
+	idtData, err := tlgcore.ReadIDT(filepath.Join(dir, id+".idt"))
+	if err != nil {
+		idtData = make(map[string]*tlgcore.WorkMetadata)
+	}
+	if t, err := tlgcore.LoadAuthorTable(filepath.Join(dir, "authtab.dir")); err == nil {
+		if r := t.Lookup(id); r != nil {
+			d.Author, d.Epithet = r.Name, r.Epithet
+		}
+	}
+	if canon, err := tlgcore.LoadCanon(dir); err == nil {
+		canon.Apply(id, idtData)
+		if a := canon.Author(id); a != nil && d.Author == id {
+			d.Author, d.Epithet = a.Name, a.Epithet
+		}
+	}
+
+	p := tlgcore.NewParser(f)
+	p.IDTData = idtData
+	p.IsLatinFile = d.Latin
+
+	var ids []string
+	if workID != "" {
+		ids = []string{tlgcore.NormalizeID(workID)}
+	} else {
+		list, err := p.ExtractList(idtData)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range list {
+			s = strings.TrimPrefix(s, "ID:")
+			ids = append(ids, strings.TrimSpace(s[:strings.Index(s, "|")]))
+		}
+		sort.SliceStable(ids, func(i, j int) bool {
+			return tlgcore.CompareCitation(ids[i], ids[j]) < 0
+		})
+	}
+
+	for _, wid := range ids {
+		w := &Work{Meta: idtData[wid], URN: tlgcore.NewURN(base, wid), From: from, To: to}
+		if w.Meta == nil {
+			w.Meta = &tlgcore.WorkMetadata{ID: wid, Title: "Work " + wid}
+		}
+		if from != "" || to != "" {
+			w.Lines, err = p.ExtractRange(wid, from, to)
+		} else {
+			w.Lines, err = p.WorkLines(wid)
+		}
+		if err != nil {
+			return nil, err
+		}
+		d.Works = append(d.Works, w)
+	}
+	if len(d.Works) == 0 {
+		return nil, fmt.Errorf("no works in %s", path)
+	}
+	return d, nil
+}
+
+// Title is the title of the document: the work title, or the author's
+// name for a whole author.
+func (d *Document) Title() string {
+	if len(d.Works) == 1 {
+		return d.Works[0].Meta.Title
+	}
+	return d.Author
+}
+
+// Units returns the citation levels that group the lines of a work,
+// outermost first: all but the last, which numbers the lines. For
+// Homer's Iliad, cited by book and line, that is the book.
+func (w *Work) Units() []tlgcore.CitationDef {
+	if n := len(w.Meta.Citations); n > 1 {
+		return w.Meta.Citations[:n-1]
+	}
+	return nil
+}
+
+// LineLevel returns the citation level that numbers the lines.
+func (w *Work) LineLevel() tlgcore.CitationDef {
+	if n := len(w.Meta.Citations); n > 0 {
+		return w.Meta.Citations[n-1]
+	}
+	return tlgcore.CitationDef{LevelChar: "z", Label: "line"}
+}
+
+// IsVerse guesses from the citation labels whether a work is verse: it
+// is cited by line or verse, and not by page, chapter or section, as
+// prose is ("Stephanus page, line").
+func (w *Work) IsVerse() bool {
+	last := strings.ToLower(w.LineLevel().Label)
+	if !strings.HasPrefix(last, "line") && !strings.HasPrefix(last, "verse") && !strings.HasPrefix(last, "vers") {
+		return false
+	}
+	for _, c := range w.Units() {
+		l := strings.ToLower(c.Label)
+		for _, prose := range []string{"page", "chapter", "section", "paragraph", "column", "letter"} {
+			if strings.Contains(l, prose) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Value returns the value of a citation level at a line, e.g. "2".
+func Value(l *tlgcore.Line, c tlgcore.CitationDef) string {
+	st, ok := l.Levels[c.LevelChar]
+	if !ok || !st.Active {
+		return ""
+	}
+	return st.String()
+}
+
+// Text is the lines to export: those with text.
+func (w *Work) Text() []*tlgcore.Line {
+	var lines []*tlgcore.Line
+	for _, l := range w.Lines {
+		if strings.TrimSpace(l.Beta) != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// Chapter is a top-level citation unit of a work, such as a book of
+// the Iliad, or the whole work if it has only one citation level.
+type Chapter struct {
+	Work  *Work
+	Label string // e.g. "Book 2"; empty for a whole work
+	Lines []*tlgcore.Line
+}
+
+// Chapters splits the lines of a work into top-level units.
+func (w *Work) Chapters() []*Chapter {
+	units := w.Units()
+	var chapters []*Chapter
+	var cur *Chapter
+	for _, l := range w.Text() {
+		label := ""
+		if len(units) > 0 {
+			label = strings.TrimSpace(units[0].Label + " " + Value(l, units[0]))
+		}
+		if cur == nil || label != cur.Label {
+			cur = &Chapter{Work: w, Label: label}
+			chapters = append(chapters, cur)
+		}
+		cur.Lines = append(cur.Lines, l)
+	}
+	return chapters
+}
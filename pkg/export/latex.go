@@ -0,0 +1,139 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var latexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`, `&`, `\&`, `%`, `\%`, `$`, `\$`, `#`, `\#`,
+	`_`, `\_`, `{`, `\{`, `}`, `\}`, `~`, `\textasciitilde{}`, `^`, `\textasciicircum{}`,
+)
+
+// LaTeX writes a document for XeLaTeX or LuaLaTeX, for handouts. The
+// author and title head the page. Verse is set line by line with the
+// line number in the margin every five lines (and at the start of each
+// unit); prose runs in paragraphs, one for each lowest citation unit,
+// which carries its citation in the margin. font is the name of a font
+// with polytonic Greek, or a font file.
+func LaTeX(w io.Writer, d *Document, font string) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, `\documentclass[11pt]{article}`)
+	fmt.Fprintln(b, `\usepackage{fontspec}`)
+	if _, err := os.Stat(font); err == nil {
+		dir, err := filepath.Abs(filepath.Dir(font))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "\\setmainfont{%s}[Path=%s/]\n", filepath.Base(font), filepath.ToSlash(dir))
+	} else {
+		fmt.Fprintf(b, "\\setmainfont{%s}\n", font)
+	}
+	fmt.Fprintln(b, `\usepackage[margin=2.5cm]{geometry}`)
+	fmt.Fprintln(b, `\setlength{\parindent}{0pt}`)
+	// \vl{n}{text}: a verse line with its number in the left margin.
+	// \leavevmode starts the paragraph, which \llap alone would not.
+	fmt.Fprintln(b, `\newcommand{\vl}[2]{\leavevmode\hangindent=2em\hangafter=1\llap{\footnotesize #1\quad}#2\par}`)
+	// \sn{cit}: the citation of a prose paragraph, in the left margin.
+	fmt.Fprintln(b, `\newcommand{\sn}[1]{\leavevmode\llap{\footnotesize\bfseries #1\quad}}`)
+	fmt.Fprintln(b, `\begin{document}`)
+
+	fmt.Fprintln(b, `\begin{center}`)
+	fmt.Fprintf(b, "{\\Large %s}\\\\[1ex]\n", latexEscaper.Replace(d.Author))
+	fmt.Fprintf(b, "{\\large\\itshape %s}", latexEscaper.Replace(d.Title()))
+	for _, work := range d.Works {
+		if work.From != "" {
+			fmt.Fprintf(b, "\\\\\n%s", latexEscaper.Replace(passageRange(work)))
+		}
+		if len(d.Works) == 1 && work.Meta.Edition != "" {
+			fmt.Fprintf(b, "\\\\[1ex]\n{\\footnotesize %s}", latexEscaper.Replace(work.Meta.Edition))
+		}
+	}
+	fmt.Fprintln(b, "\n\\end{center}")
+
+	for _, work := range d.Works {
+		if len(d.Works) > 1 {
+			fmt.Fprintf(b, "\n\\section*{%s}\n", latexEscaper.Replace(work.Meta.Title))
+		}
+		if work.IsVerse() {
+			latexVerse(b, work)
+		} else {
+			latexProse(b, work)
+		}
+	}
+
+	fmt.Fprintln(b, `\end{document}`)
+	return b.Flush()
+}
+
+// latexVerse sets the chapters of a verse work, with a heading for each
+// top-level unit and for each change of the lower ones, e.g. the poems
+// of a book.
+func latexVerse(b *bufio.Writer, w *Work) {
+	units := w.Units()
+	for _, ch := range w.Chapters() {
+		if ch.Label != "" {
+			fmt.Fprintf(b, "\n\\subsection*{%s}\n", latexEscaper.Replace(ch.Label))
+		}
+		prev := ""
+		first := true
+		for _, l := range ch.Lines {
+			var sub []string
+			for _, u := range units[min(1, len(units)):] {
+				sub = append(sub, strings.TrimSpace(u.Label+" "+Value(l, u)))
+			}
+			if s := strings.Join(sub, ", "); s != prev {
+				fmt.Fprintf(b, "\n\\subsubsection*{%s}\n", latexEscaper.Replace(s))
+				prev = s
+				first = true
+			}
+
+			n := Value(l, w.LineLevel())
+			num := ""
+			if i, err := strconv.Atoi(n); first || err == nil && i%5 == 0 {
+				num = n
+			}
+			first = false
+			fmt.Fprintf(b, "\\vl{%s}{%s}\n", latexEscaper.Replace(num), latexEscaper.Replace(strings.TrimSpace(l.Text)))
+		}
+	}
+}
+
+// latexProse sets a prose work in paragraphs, one for each lowest
+// citation unit, numbered with its citation: "327a", "1.2.3".
+func latexProse(b *bufio.Writer, w *Work) {
+	units := w.Units()
+	prev := ""
+	inPar, hyphen := false, false
+	for _, l := range w.Text() {
+		var vals []string
+		for _, u := range units {
+			vals = append(vals, Value(l, u))
+		}
+		cit := strings.Join(vals, ".")
+		if !inPar || cit != prev {
+			if inPar {
+				fmt.Fprint(b, "\n\n")
+			}
+			if cit != "" {
+				fmt.Fprintf(b, "\\sn{%s}", latexEscaper.Replace(cit))
+			}
+			inPar = true
+			prev = cit
+		} else if !hyphen {
+			fmt.Fprint(b, "\n")
+		}
+		// A word hyphenated at the end of a line is joined.
+		var text string
+		text, hyphen = strings.CutSuffix(strings.TrimSpace(l.Text), "-")
+		fmt.Fprint(b, latexEscaper.Replace(text))
+	}
+	if inPar {
+		fmt.Fprint(b, "\n")
+	}
+}
@@ -0,0 +1,283 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"tlgread/pkg/tlgcore"
+)
+
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// TEI writes a document as TEI P5 XML in the style of EpiDoc: a
+// teiHeader from authtab.dir, the IDT file and the canon, and for each
+// work a div of type edition holding textpart divs for the citation
+// units. Verse lines become <l n="...">; prose becomes a <p> per unit
+// with an <lb n="..."/> before each line.
+func TEI(w io.Writer, d *Document) error {
+	b := bufio.NewWriter(w)
+	x := &teiWriter{b: b}
+
+	x.printf(0, `<?xml version="1.0" encoding="UTF-8"?>`)
+	x.printf(0, `<TEI xmlns="http://www.tei-c.org/ns/1.0">`)
+	x.header(d)
+	lang := "grc"
+	if d.Latin {
+		lang = "la"
+	}
+	x.printf(1, `<text xml:lang="%s">`, lang)
+	x.printf(2, `<body>`)
+	for _, work := range d.Works {
+		x.work(d, work)
+	}
+	x.printf(2, `</body>`)
+	x.printf(1, `</text>`)
+	x.printf(0, `</TEI>`)
+	return b.Flush()
+}
+
+type teiWriter struct {
+	b *bufio.Writer
+}
+
+// printf writes a line indented by depth.
+func (x *teiWriter) printf(depth int, format string, args ...any) {
+	x.b.WriteString(strings.Repeat("  ", depth))
+	fmt.Fprintf(x.b, format, args...)
+	x.b.WriteByte('\n')
+}
+
+// element writes <name>text</name> if text is not empty.
+func (x *teiWriter) element(depth int, name, text string) {
+	if text != "" {
+		x.printf(depth, "<%s>%s</%s>", name, xmlEscaper.Replace(text), name)
+	}
+}
+
+func (x *teiWriter) header(d *Document) {
+	x.printf(1, `<teiHeader>`)
+	x.printf(2, `<fileDesc>`)
+	x.printf(3, `<titleStmt>`)
+	x.element(4, "title", d.Title())
+	x.element(4, "author", d.Author)
+	x.printf(3, `</titleStmt>`)
+	x.printf(3, `<publicationStmt>`)
+	x.element(4, "p", "Converted from "+d.File+" by lyceum export.")
+	x.printf(3, `</publicationStmt>`)
+	x.printf(3, `<sourceDesc>`)
+	for _, w := range d.Works {
+		x.printf(4, `<bibl>`)
+		x.element(5, "author", d.Author)
+		x.element(5, "title", w.Meta.Title)
+		x.element(5, "editor", w.Meta.Editor)
+		x.element(5, "note", w.Meta.Edition)
+		x.printf(5, `<idno type="CTS">%s</idno>`, xmlEscaper.Replace(w.URN.String()))
+		x.printf(4, `</bibl>`)
+	}
+	x.printf(3, `</sourceDesc>`)
+	x.printf(2, `</fileDesc>`)
+
+	x.printf(2, `<encodingDesc>`)
+	for _, w := range d.Works {
+		x.printf(3, `<refsDecl n="%s">`, xmlEscaper.Replace(w.URN.String()))
+		for i, c := range w.Meta.Citations {
+			delim := ""
+			if i < len(w.Meta.Citations)-1 {
+				delim = ` delim="."`
+			}
+			x.printf(4, `<refState unit="%s"%s/>`, xmlEscaper.Replace(teiUnit(c.Label)), delim)
+		}
+		x.printf(3, `</refsDecl>`)
+	}
+	x.printf(2, `</encodingDesc>`)
+
+	x.printf(2, `<profileDesc>`)
+	if len(d.Works) == 1 && d.Works[0].Meta.Date != "" {
+		x.printf(3, `<creation>`)
+		x.element(4, "date", d.Works[0].Meta.Date)
+		x.printf(3, `</creation>`)
+	}
+	x.printf(3, `<langUsage>`)
+	x.printf(4, `<language ident="grc">Ancient Greek</language>`)
+	x.printf(4, `<language ident="la">Latin</language>`)
+	x.printf(3, `</langUsage>`)
+	var genres []string
+	for _, w := range d.Works {
+		if w.Meta.Genre != "" && !contains(genres, w.Meta.Genre) {
+			genres = append(genres, w.Meta.Genre)
+		}
+	}
+	if len(genres) > 0 {
+		x.printf(3, `<textClass>`)
+		x.printf(4, `<keywords>`)
+		for _, g := range genres {
+			x.element(5, "term", g)
+		}
+		x.printf(4, `</keywords>`)
+		x.printf(3, `</textClass>`)
+	}
+	x.printf(2, `</profileDesc>`)
+	x.printf(1, `</teiHeader>`)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// teiUnit turns a citation label such as "Stephanus page" into a unit
+// or subtype name: "stephanus-page".
+func teiUnit(label string) string {
+	return strings.Join(strings.Fields(strings.ToLower(label)), "-")
+}
+
+// nAttr returns ` n="v"`, or nothing if v is empty.
+func nAttr(v string) string {
+	if v == "" {
+		return ""
+	}
+	return ` n="` + xmlEscaper.Replace(v) + `"`
+}
+
+func (x *teiWriter) work(d *Document, w *Work) {
+	x.printf(3, `<div type="edition" n="%s">`, xmlEscaper.Replace(w.URN.String()))
+	x.element(4, "head", w.Meta.Title)
+
+	units := w.Units()
+	verse := w.IsVerse()
+	spans := tlgcore.NewSpanReader(d.Latin)
+	var path []string // values of the open textpart divs
+	inP := false
+
+	for _, l := range w.Text() {
+		cur := make([]string, len(units))
+		for i, u := range units {
+			cur[i] = Value(l, u)
+		}
+		same := 0
+		for same < len(path) && path[same] == cur[same] {
+			same++
+		}
+		if same < len(path) || path == nil {
+			if inP {
+				x.printf(4+len(path), `</p>`)
+				inP = false
+			}
+			for i := len(path) - 1; i >= same; i-- {
+				x.printf(4+i, `</div>`)
+			}
+			for i := same; i < len(units); i++ {
+				x.printf(4+i, `<div type="textpart" subtype="%s"%s>`,
+					xmlEscaper.Replace(teiUnit(units[i].Label)), nAttr(cur[i]))
+			}
+			path = cur
+		}
+
+		n := Value(l, w.LineLevel())
+		depth := 4 + len(units)
+		if verse {
+			x.printf(depth, `<l%s>%s</l>`, nAttr(n), teiLine(spans.Spans(l.Beta), d.Latin))
+			continue
+		}
+		if !inP {
+			x.printf(depth, `<p>`)
+			inP = true
+		}
+		x.printf(depth+1, `<lb%s/>%s`, nAttr(n), teiLine(spans.Spans(l.Beta), d.Latin))
+	}
+	if inP {
+		x.printf(4+len(path), `</p>`)
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		x.printf(4+i, `</div>`)
+	}
+	x.printf(3, `</div>`)
+}
+
+// teiElement is an inline element for a style of spans.
+type teiElement struct {
+	style      tlgcore.Style
+	start, end string
+}
+
+// teiElements map span styles to elements, outermost first. Brackets
+// follow the Leiden conventions: [ ] is text lost and restored by the
+// editor, [2 ]2 omitted by the scribe, [3 ]3 (braces) surplus and [4 ]4
+// (double brackets) erased.
+var teiElements = []teiElement{
+	{tlgcore.Marginal, `<note place="margin">`, `</note>`},
+	{tlgcore.Quote, `<q>`, `</q>`},
+	{tlgcore.Supplied, `<supplied reason="lost">`, `</supplied>`},
+	{tlgcore.Omitted, `<supplied reason="omitted">`, `</supplied>`},
+	{tlgcore.Surplus, `<surplus>`, `</surplus>`},
+	{tlgcore.Erased, `<del rend="erasure">`, `</del>`},
+	{tlgcore.Speaker, `<label type="speaker">`, `</label>`},
+	{tlgcore.Title, `<title>`, `</title>`},
+	{0, "", ""}, // <foreign>, for text in the other language
+	{tlgcore.Bold, `<hi rend="bold">`, `</hi>`},
+	{tlgcore.Italic, `<hi rend="italic">`, `</hi>`},
+	{tlgcore.Superscript, `<hi rend="superscript">`, `</hi>`},
+	{tlgcore.Subscript, `<hi rend="subscript">`, `</hi>`},
+}
+
+// teiLine renders the spans of a line. Elements are opened in the order
+// of teiElements, so that they nest even where the codes do not; an element still open at
+// the end of the line is closed there, and the next line's spans open
+// it again. The brackets and quotation marks themselves are dropped and
+// a lacuna becomes <gap/>.
+func teiLine(spans []tlgcore.Span, latinFile bool) string {
+	var b strings.Builder
+	var open []teiElement
+	inGap := false
+	for _, s := range spans {
+		if s.Style&tlgcore.Sign != 0 {
+			continue
+		}
+
+		var want []teiElement
+		for _, e := range teiElements {
+			switch {
+			case e.style == 0 && s.Latin != latinFile:
+				lang := "grc"
+				if s.Latin {
+					lang = "la"
+				}
+				want = append(want, teiElement{0, `<foreign xml:lang="` + lang + `">`, `</foreign>`})
+			case s.Style&e.style != 0:
+				want = append(want, e)
+			}
+		}
+
+		same := 0
+		for same < len(open) && same < len(want) && open[same] == want[same] {
+			same++
+		}
+		for i := len(open) - 1; i >= same; i-- {
+			b.WriteString(open[i].end)
+		}
+		for _, e := range want[same:] {
+			b.WriteString(e.start)
+		}
+		open = want
+
+		if s.Style&tlgcore.Lacuna != 0 {
+			if !inGap {
+				b.WriteString(`<gap reason="lost"/>`)
+			}
+			inGap = true
+			continue
+		}
+		inGap = false
+		b.WriteString(xmlEscaper.Replace(s.Text))
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString(open[i].end)
+	}
+	return strings.TrimSpace(b.String())
+}
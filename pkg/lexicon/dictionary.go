@@ -0,0 +1,100 @@
+package lexicon
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"tlgread/pkg/morph"
+	"tlgread/pkg/tlgcore"
+)
+
+// Dictionary looks up inflected forms the way cmd/search does: the
+// analyses file gives the lemmata of a form, the XML dictionary their
+// entries.
+type Dictionary struct {
+	Analyses *morph.Analyses // nil if there is no analyses file
+	Path     string          // grc.lsj.xml or lat.ls.perseus-eng1.xml
+	Index    map[string]int64
+	Latin    bool
+}
+
+// OpenDictionary opens the analyses and the dictionary index. Missing
+// files are not an error; lookups then find nothing.
+func OpenDictionary(analPath, analIdtPath, dicPath, dicIdtPath string, latin bool) *Dictionary {
+	d := &Dictionary{Path: dicPath, Index: make(map[string]int64), Latin: latin}
+	if _, err := os.Stat(analPath); err == nil {
+		if a, err := morph.OpenAnalyses(analPath, analIdtPath); err == nil {
+			d.Analyses = a
+		}
+	}
+	if _, err := os.Stat(dicIdtPath); err == nil {
+		d.Index = LoadLSJIndex(dicIdtPath)
+	}
+	return d
+}
+
+// Analyze returns the analyses of a word given in Unicode or Beta Code.
+func (d *Dictionary) Analyze(word string) ([]morph.MorphResult, error) {
+	if d.Analyses == nil {
+		return nil, fmt.Errorf("no analyses file")
+	}
+	return d.Analyses.LookupWord(morph.BetaQuery(word))
+}
+
+// Define returns the entries of the lemmata of a word. A word without
+// analyses is looked up as a headword.
+func (d *Dictionary) Define(word string) ([]Entry, error) {
+	var lemmata []string
+	if results, err := d.Analyze(word); err == nil {
+		for _, r := range results {
+			lemmata = append(lemmata, r.Lemma)
+		}
+	}
+	if len(lemmata) == 0 {
+		lemmata = append(lemmata, morph.BetaQuery(word))
+	}
+
+	seen := make(map[int64]bool)
+	var entries []Entry
+	for _, lemma := range lemmata {
+		found, err := Lookup(d.Path, lemma, d.Index, seen, !d.Latin)
+		entries = append(entries, found...)
+		if err != nil {
+			return entries, err
+		}
+	}
+	return entries, nil
+}
+
+// FormatAnalysis renders an analysis of a word as cmd/search prints it.
+func (d *Dictionary) FormatAnalysis(word string, r morph.MorphResult) string {
+	searchWord := morph.BetaQuery(word)
+	lemma := strings.Fields(r.Lemma)[0]
+	if d.Latin {
+		return fmt.Sprintf("Latin: %s | Lemma: %s (%s)", searchWord, lemma, r.Morphology)
+	}
+	return fmt.Sprintf("Greek: %s | Lemma: %s (%s)", tlgcore.ToGreek(searchWord), tlgcore.ToGreek(lemma), r.Morphology)
+}
+
+// Report returns what cmd/search prints for a word: its analyses
+// followed by the dictionary entries of their lemmata.
+func (d *Dictionary) Report(word string) string {
+	results, err := d.Analyze(word)
+	if err != nil {
+		return fmt.Sprintf("%s: morphology not found.\n", word)
+	}
+
+	var b strings.Builder
+	for _, r := range results {
+		b.WriteString(d.FormatAnalysis(word, r) + "\n")
+	}
+	entries, err := d.Define(word)
+	for _, e := range entries {
+		fmt.Fprintf(&b, "\n[ENTRY: %s]\n%s\n", e.Headword, e.Text())
+	}
+	if err != nil {
+		b.WriteString(err.Error() + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,290 @@
+// Package lexicon looks up entries in the Perseus LSJ and Lewis & Short
+// XML dictionaries through the offset index written by cmd/indexer.
+package lexicon
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"tlgread/pkg/tlgcore"
+)
+
+type LSJEntry struct {
+	Key   string `xml:"key,attr"`
+	Orth  string `xml:"orth"`
+	Sense string `xml:",innerxml"`
+}
+
+// Entry is a dictionary entry found by Lookup.
+type Entry struct {
+	Headword string // Unicode headword
+	Offset   int64
+	LSJEntry
+}
+
+// Text returns the entry as plain text (see ProcessSense).
+func (e *Entry) Text() string {
+	return ProcessSense(e.Sense)
+}
+
+func LoadLSJIndex(path string) map[string]int64 {
+	index := make(map[string]int64)
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("Warning: Could not open index file at %s\n", path)
+		return index
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Split by ' => ' which is what our indexer uses
+		parts := strings.Split(line, " => ")
+		if len(parts) == 2 {
+			// Clean the key: remove surrounding single quotes
+			key := strings.Trim(parts[0], "'")
+			offset, _ := strconv.ParseInt(parts[1], 10, 64)
+			index[key] = offset
+		}
+	}
+	return index
+}
+
+// Lookup returns the entries of a lemma (Beta Code for LSJ). Entries
+// whose offsets are in seenOffsets are skipped; found entries are added
+// to it, so several lemmata can share one map.
+func Lookup(xmlPath string, rawLemma string, lsjIndex map[string]int64, seenOffsets map[int64]bool, isLSJ bool) ([]Entry, error) {
+	var strictKey string
+
+	fields := strings.Fields(rawLemma)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	lemma := fields[0]
+
+	if isLSJ {
+		strictKey = tlgcore.NormalizeStrict(lemma)
+	} else {
+		strictKey = tlgcore.NormalizeLatin(lemma)
+	}
+
+	fuzzyKey := tlgcore.NormalizeFuzzy(lemma)
+
+	var offsets []int64
+	localSeen := make(map[int64]bool)
+
+	addUnique := func(val int64) {
+		if !localSeen[val] {
+			offsets = append(offsets, val)
+			localSeen[val] = true
+		}
+	}
+
+	// Check the base key (e.g., "legw")
+	if val, ok := lsjIndex[strictKey]; ok {
+		addUnique(val)
+	}
+
+	// Check numbered keys (e.g., "legw2", "legw3", ...) ... Do we need this?
+	for i := 2; ; i++ {
+		key := strictKey + strconv.Itoa(i)
+		val, ok := lsjIndex[key]
+		if !ok {
+			break
+		}
+		addUnique(val)
+	}
+
+	if len(offsets) == 0 {
+		if val, ok := lsjIndex[fuzzyKey]; ok {
+			addUnique(val)
+		} else {
+			// Prefix scan fallback
+			for k, off := range lsjIndex {
+				if strings.HasPrefix(k, fuzzyKey) {
+					addUnique(off)
+					break // Stop after first fuzzy match
+				}
+			}
+		}
+	}
+
+	if len(offsets) == 0 {
+		return nil, nil
+	}
+
+	f, err := os.Open(xmlPath)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening LSJ file: %v", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	for _, offset := range offsets {
+		if seenOffsets[offset] {
+			continue
+		}
+
+		_, err = f.Seek(offset, 0)
+		if err != nil {
+			return entries, fmt.Errorf("Seek error: %v", err)
+		}
+
+		decoder := xml.NewDecoder(f)
+		var entry LSJEntry
+		err = decoder.Decode(&entry)
+		if err != nil {
+			return entries, nil
+		}
+
+		seenOffsets[offset] = true
+
+		e := Entry{Headword: entry.Key, Offset: offset, LSJEntry: entry}
+		if isLSJ {
+			e.Headword = tlgcore.ToGreek(entry.Key)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func ProcessSense(rawXml string) string {
+	// 1. Convert Greek tags to Unicode Greek first
+	reForeign := regexp.MustCompile(`<foreign lang="greek">([^<]+)</foreign>`)
+	processed := reForeign.ReplaceAllStringFunc(rawXml, func(match string) string {
+		code := reForeign.FindStringSubmatch(match)[1]
+		return tlgcore.ToGreek(code)
+	})
+
+	// 2. Structural Replacements: Turn tags into layout markers
+	// Treat each sense as a new paragraph with a newline
+	processed = strings.ReplaceAll(processed, "<sense", "\n\n  • <sense")
+
+	// Ensure bibliographic references have a space after them
+	processed = strings.ReplaceAll(processed, "</bibl>", " ")
+	processed = strings.ReplaceAll(processed, "</cit>", " ")
+
+	// 3. Strip all remaining XML tags
+	stripTags := regexp.MustCompile("<[^>]*>")
+	clean := stripTags.ReplaceAllString(processed, "")
+
+	// 4. Decode XML entities
+	clean = strings.ReplaceAll(clean, "&gt;", ">")
+	clean = strings.ReplaceAll(clean, "&lt;", "<")
+	clean = strings.ReplaceAll(clean, "&amp;", "&")
+	clean = strings.ReplaceAll(clean, "&quot;", "\"")
+
+	// 5. Clean up horizontal whitespace
+	// We preserve the double newlines we created, but collapse extra spaces on lines
+	lines := strings.Split(clean, "\n")
+	var finalLines []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" {
+			// Collapse multiple spaces within the line
+			reSpace := regexp.MustCompile(`\s+`)
+			finalLines = append(finalLines, reSpace.ReplaceAllString(trimmed, " "))
+		}
+	}
+
+	return strings.Join(finalLines, "\n\n")
+}
+
+// ShortGloss returns a one-line gloss of an entry: its first
+// translations (<tr> elements) if it has any, otherwise the beginning
+// of its first sense as given by ProcessSense.
+func ShortGloss(rawXml string, maxLen int) string {
+	reTr := regexp.MustCompile(`<tr[^>]*>([^<]+)</tr>`)
+	var trs []string
+	seen := make(map[string]bool)
+	for _, m := range reTr.FindAllStringSubmatch(rawXml, -1) {
+		tr := strings.TrimSpace(strings.Trim(m[1], ",;: "))
+		if tr == "" || seen[tr] {
+			continue
+		}
+		seen[tr] = true
+		trs = append(trs, tr)
+		if len(trs) == 3 {
+			break
+		}
+	}
+	gloss := strings.Join(trs, ", ")
+
+	if gloss == "" {
+		paras := strings.Split(ProcessSense(rawXml), "\n\n")
+		for _, p := range paras {
+			if strings.HasPrefix(p, "•") {
+				gloss = strings.TrimSpace(strings.TrimPrefix(p, "•"))
+				break
+			}
+		}
+		if gloss == "" && len(paras) > 0 {
+			gloss = paras[0]
+		}
+	}
+
+	if maxLen > 0 && utf8.RuneCountInString(gloss) > maxLen {
+		gloss = string([]rune(gloss)[:maxLen-1]) + "…"
+	}
+	return gloss
+}
+
+// BuildIndex writes the index of a dictionary read by LoadLSJIndex:
+// LSJ entries (<div2>) under their strict and fuzzy keys, Lewis & Short
+// entries (<div1>) under their Latin key.
+func BuildIndex(xmlPath, indexPath string) error {
+	f, err := os.Open(xmlPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	out, err := os.Create(indexPath)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(out)
+
+	reader := bufio.NewReader(f)
+	var offset int64
+	re := regexp.MustCompile(`key="([^"]+)"`)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+
+		if strings.HasPrefix(line, "<div2") {
+			if match := re.FindStringSubmatch(line); len(match) > 1 {
+				strictKey := tlgcore.NormalizeStrict(match[1])
+				fuzzyKey := tlgcore.NormalizeFuzzy(match[1])
+
+				fmt.Fprintf(w, "'%s' => %d\n", strictKey, offset)
+				if fuzzyKey != strictKey {
+					fmt.Fprintf(w, "'%s' => %d\n", fuzzyKey, offset)
+				}
+			}
+		}
+
+		if strings.HasPrefix(line, "<div1") {
+			if match := re.FindStringSubmatch(line); len(match) > 1 {
+				fmt.Fprintf(w, "'%s' => %d\n", tlgcore.NormalizeLatin(match[1]), offset)
+			}
+		}
+		offset += int64(len(line))
+	}
+
+	if err := w.Flush(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
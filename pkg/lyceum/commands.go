@@ -0,0 +1,584 @@
+package lyceum
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"tlgread/pkg/export"
+	"tlgread/pkg/lexicon"
+	"tlgread/pkg/morph"
+	"tlgread/pkg/server"
+	"tlgread/pkg/tlgcore"
+	"tlgread/pkg/tlgindex"
+)
+
+// A Command is a subcommand of lyceum. Run gets the command name in
+// args[0], like os.Args, so the old single-purpose binaries can call it
+// with os.Args unchanged.
+type Command struct {
+	Name    string
+	Args    string
+	Summary string
+	Run     func(cfg *Config, args []string) error
+}
+
+var Commands = []*Command{
+	{"auth", "[tlg|phi]", "list the authors of a corpus", Auth},
+	{"works", "author", "list the works of an author", Works},
+	{"read", "author work [from [to]]", "print a work or a passage", Read},
+	{"export", "author [work [from [to]]]", "write a work as TEI XML, EPUB or LaTeX", Export},
+	{"search", "word", "print the analyses and dictionary entries of a word", Search},
+	{"lemma", "word", "list the inflected forms of a lemma", Lemma},
+	{"index", "", "index the dictionaries or a corpus", Index},
+	{"serve", "", "serve the corpus and dictionaries over HTTP", Serve},
+}
+
+// Lookup returns the command with the given name, or nil.
+func Lookup(name string) *Command {
+	for _, c := range Commands {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// isSet reports whether a flag was given on the command line.
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// Auth prints the author table of a corpus.
+func Auth(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ExitOnError)
+	fPath := fs.String("f", "", "authtab.dir path (default: that of the tlg or phi corpus)")
+	verbose := fs.Bool("v", false, "print section markers, alternate names and remarks")
+	query := fs.String("q", "", "search authors by name, e.g. thucyd or Θουκυδ")
+	fs.Parse(args[1:])
+
+	path := *fPath
+	if path == "" {
+		dir := cfg.TLG
+		if fs.NArg() > 0 && strings.EqualFold(fs.Arg(0), "phi") {
+			dir = cfg.PHI
+		}
+		if dir == "" {
+			return errors.New("corpus directory not found; set it in " + ConfigPath())
+		}
+		path = filepath.Join(dir, "authtab.dir")
+	}
+
+	t, err := tlgcore.ReadAuthorTable(path)
+	if err != nil {
+		return err
+	}
+	records := t.Records
+	if *query != "" {
+		records = nil
+		for _, m := range t.Search(*query) {
+			records = append(records, m.Record)
+		}
+		if len(records) == 0 {
+			return fmt.Errorf("no author matches %q", *query)
+		}
+	}
+
+	section := ""
+	for _, r := range records {
+		if *verbose && r.Section != section {
+			section = r.Section
+			fmt.Printf("*%s\n", section)
+		}
+		fmt.Printf("%-8s | %s %s\n", r.ID, r.Name, r.Epithet)
+		if !*verbose {
+			continue
+		}
+		for _, alt := range r.AltNames {
+			fmt.Printf("%-8s |   = %s\n", "", alt)
+		}
+		if r.Remarks != "" {
+			fmt.Printf("%-8s |   %s\n", "", r.Remarks)
+		}
+	}
+	return nil
+}
+
+// Works lists the works of an author.
+func Works(cfg *Config, args []string) error {
+	return view(cfg, args, true)
+}
+
+// Read prints a work, a citation range or a CTS URN passage.
+func Read(cfg *Config, args []string) error {
+	return view(cfg, args, false)
+}
+
+func printLines(p *tlgcore.Parser, lines []*tlgcore.Line, showURN bool) {
+	for _, l := range lines {
+		if strings.TrimSpace(l.Text) == "" {
+			continue
+		}
+		if showURN {
+			fmt.Printf("%s %s\n", p.URN(l), l.Text)
+		} else {
+			fmt.Print(tlgcore.FormatLine(l))
+		}
+	}
+}
+
+// printBibliography prints the canon data of a work, if any.
+func printBibliography(meta *tlgcore.WorkMetadata) {
+	for _, f := range []struct{ label, value string }{
+		{"Date:", meta.Date},
+		{"Genre:", meta.Genre},
+		{"Editor:", meta.Editor},
+		{"Edition:", meta.Edition},
+	} {
+		if f.value != "" {
+			fmt.Printf("%-7s %s\n", f.label, f.value)
+		}
+	}
+}
+
+func view(cfg *Config, args []string, listWorks bool) error {
+	fs := flag.NewFlagSet(args[0], flag.ExitOnError)
+	fPath := fs.String("f", "", "TLG .txt")
+	wID := fs.String("w", "", "Work ID")
+	list := fs.Bool("list", listWorks, "List")
+	from := fs.String("from", "", "first citation, e.g. 2.100")
+	to := fs.String("to", "", "last citation, e.g. 2.150")
+	urn := fs.String("urn", "", "CTS URN, e.g. urn:cts:greekLit:tlg0012.tlg001:1.1-1.10")
+	dPath := fs.String("d", "", "corpus directory used with -urn (default: from the config)")
+	showURN := fs.Bool("showurn", false, "print CTS URNs instead of citations")
+	fs.Parse(args[1:])
+
+	if err := passage(cfg, fs.Args(), *dPath, *urn, fPath, wID, from, to); err != nil {
+		return err
+	}
+
+	if *fPath == "" {
+		return errors.New("Usage: lyceum works tlg0012 | lyceum read tlg0012 1 [2.100 [2.150]]\n" +
+			"       lyceum read -f tlg[0000-9999].txt [-list] or [-w 1 [-from 2.100] [-to 2.150]]\n" +
+			"       lyceum read [-d dir] -urn urn:cts:greekLit:tlg0012.tlg001:1.1-1.10")
+	}
+
+	f, err := os.Open(*fPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dir, base := filepath.Split(*fPath)
+	tlgID := strings.TrimSuffix(base, filepath.Ext(base))
+
+	idtPath := filepath.Join(dir, tlgID+".idt")
+	idtData, err := tlgcore.ReadIDT(idtPath)
+
+	if err != nil {
+		fmt.Printf("Warning: Failed to read IDT file %s: %v\n", idtPath, err)
+		idtData = make(map[string]*tlgcore.WorkMetadata)
+	}
+
+	author := "Unknown"
+	if t, err := tlgcore.LoadAuthorTable(filepath.Join(dir, "authtab.dir")); err == nil {
+		author = t.Name(tlgID)
+	}
+	if canon, err := tlgcore.LoadCanon(dir); err == nil {
+		canon.Apply(tlgID, idtData)
+		if a := canon.Author(tlgID); a != nil && (author == "Unknown" || author == tlgID) {
+			author = a.Name
+		}
+	}
+
+	p := tlgcore.NewParser(f)
+	p.IDTData = idtData
+
+	latinBase := []string{"LAT", "CIV", "PHI"}
+
+	for _, pref := range latinBase {
+		if strings.HasPrefix(strings.ToUpper(base), pref) {
+			p.IsLatinFile = true
+			break
+		}
+	}
+
+	if *list {
+		fmt.Printf("File: %s (%s)\n", base, author)
+		fmt.Println("----------------------------------------")
+
+		works, err := p.ExtractList(idtData)
+		if err != nil {
+			return err
+		}
+		for _, w := range works {
+			fmt.Println(w)
+		}
+		return nil
+	}
+
+	cleanWID := tlgcore.NormalizeID(*wID)
+
+	title := "(Unknown Title)"
+	meta := idtData[cleanWID]
+	if meta != nil {
+		title = meta.Title
+	}
+
+	fmt.Printf("Author: %s\nWork:   %s (ID: %s)\n", author, title, cleanWID)
+	if meta != nil {
+		printBibliography(meta)
+	}
+
+	if meta != nil && len(meta.Citations) > 0 {
+		for _, c := range meta.Citations {
+			fmt.Printf("%s (%s) ", c.Label, c.LevelChar)
+		}
+		fmt.Printf("\n")
+	}
+	fmt.Println("----------------------------------------")
+
+	if *from != "" || *to != "" {
+		lines, err := p.ExtractRange(cleanWID, *from, *to)
+		if err != nil {
+			fmt.Println("Error:", err)
+		}
+		printLines(p, lines, *showURN)
+		return nil
+	}
+
+	if *showURN {
+		lines, err := p.WorkLines(cleanWID)
+		if err != nil {
+			fmt.Println("Error:", err)
+		}
+		printLines(p, lines, true)
+		return nil
+	}
+
+	text, err := p.ExtractWork(cleanWID)
+	if err != nil {
+		fmt.Println("Error:", err)
+	} else {
+		fmt.Print(text)
+	}
+	return nil
+}
+
+// passage fills in the file, work and citations of a passage from the
+// positional arguments (author [work [from [to]]]) or a CTS URN. An
+// author number is looked up in the configured corpora; with a URN the
+// file is in dir, by default the corpus of the URN's namespace.
+func passage(cfg *Config, rest []string, dir, urn string, fPath, wID, from, to *string) error {
+	for _, v := range []*string{fPath, wID, from, to} {
+		if len(rest) == 0 {
+			break
+		}
+		if *v == "" {
+			*v = rest[0]
+			rest = rest[1:]
+		}
+	}
+	if *fPath != "" && !strings.ContainsAny(*fPath, `/\.`) {
+		path, err := cfg.Text(*fPath)
+		if err != nil {
+			return err
+		}
+		*fPath = path
+	}
+
+	if urn != "" {
+		u, err := tlgcore.ParseURN(urn)
+		if err != nil {
+			return err
+		}
+		if dir == "" {
+			dir = cfg.TLG
+			if u.Namespace == "latinLit" {
+				dir = cfg.PHI
+			}
+		}
+		*fPath = u.Path(dir)
+		*wID = u.WorkID()
+		*from = u.From
+		*to = u.To
+		if *to == "" {
+			*to = u.From
+		}
+	}
+	return nil
+}
+
+// Export writes a work, a passage or all the works of an author in
+// another format: TEI XML, EPUB or LaTeX.
+func Export(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ExitOnError)
+	fPath := fs.String("f", "", "TLG .txt")
+	wID := fs.String("w", "", "work ID (default: all works)")
+	from := fs.String("from", "", "first citation, e.g. 2.100")
+	to := fs.String("to", "", "last citation, e.g. 2.150")
+	urn := fs.String("urn", "", "CTS URN, e.g. urn:cts:greekLit:tlg0012.tlg001:1.1-1.10")
+	dPath := fs.String("d", "", "corpus directory used with -urn (default: from the config)")
+	format := fs.String("fmt", "tei", "output format: tei, epub, latex")
+	font := fs.String("font", cfg.File("font"), "font file to embed in an EPUB, or font file or name for LaTeX")
+	out := fs.String("o", "", "output file (default: standard output)")
+	fs.Parse(args[1:])
+
+	if err := passage(cfg, fs.Args(), *dPath, *urn, fPath, wID, from, to); err != nil {
+		return err
+	}
+	if *fPath == "" {
+		return errors.New("Usage: lyceum export [-fmt tei|epub|latex] [-o file] tlg0012 [1 [2.100 [2.150]]]\n" +
+			"       lyceum export -f tlg[0000-9999].txt [-w 1 [-from 2.100] [-to 2.150]]\n" +
+			"       lyceum export [-d dir] -urn urn:cts:greekLit:tlg0012.tlg001:1.1-1.10")
+	}
+
+	var write func(io.Writer, *export.Document) error
+	switch *format {
+	case "tei":
+		write = export.TEI
+	case "epub":
+		// The book embeds the font: readers seldom have one with
+		// polytonic Greek.
+		if _, err := os.Stat(*font); err != nil {
+			return fmt.Errorf("no font to embed: %v (run fetchdep, or give -font)", err)
+		}
+		write = func(w io.Writer, d *export.Document) error {
+			return export.EPUB(w, d, *font)
+		}
+	case "latex":
+		if _, err := os.Stat(*font); err != nil && !isSet(fs, "font") {
+			*font = "Gentium Plus"
+		}
+		write = func(w io.Writer, d *export.Document) error {
+			return export.LaTeX(w, d, *font)
+		}
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	doc, err := export.Load(*fPath, *wID, *from, *to)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		return write(os.Stdout, doc)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := write(f, doc); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Search prints the analyses of a word and the dictionary entries of
+// its lemmata. With -lat the Latin files are the defaults.
+func Search(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ExitOnError)
+	wordRaw := fs.String("w", "", "word in Beta Code / Greek")
+	lsjPath := fs.String("dic", cfg.File("lsj"), "LSJ XML path")
+	idtPath := fs.String("idt", cfg.File("greek-analyses-idt"), "idt file")
+	analPath := fs.String("a", cfg.File("greek-analyses"), "analyses txt file")
+	lsjidtPath := fs.String("dicidt", cfg.File("lsj-idt"), "LSJ idt file")
+	printdic := fs.Bool("entry", true, "print dictionary entries or not")
+	isLatin := fs.Bool("lat", false, "use L-S dictionary")
+	fs.Parse(args[1:])
+
+	if *wordRaw == "" && fs.NArg() > 0 {
+		*wordRaw = fs.Arg(0)
+	}
+	if *isLatin {
+		for name, key := range map[string]string{"dic": "ls", "idt": "latin-analyses-idt", "a": "latin-analyses", "dicidt": "ls-idt"} {
+			if !isSet(fs, name) {
+				fs.Set(name, cfg.File(key))
+			}
+		}
+	}
+
+	lsjIndex := lexicon.LoadLSJIndex(*lsjidtPath)
+
+	searchWord := morph.BetaQuery(*wordRaw)
+
+	analyses, err := morph.OpenAnalyses(*analPath, *idtPath)
+	if err != nil {
+		return err
+	}
+
+	results, err := analyses.Lookup(searchWord)
+	if err != nil {
+		return errors.New("Morphology not found.")
+	}
+
+	// Output Morph and then LSJ
+
+	seenLSJEntries := make(map[int64]bool)
+
+	for _, r := range results {
+		lemmaDisplay := strings.Fields(r.Lemma)[0]
+		if !*isLatin {
+			fmt.Printf("Greek: %s | Lemma: %s (%s)\n", tlgcore.ToGreek(searchWord), tlgcore.ToGreek(lemmaDisplay), r.Morphology)
+		} else {
+			fmt.Printf("Latin: %s | Lemma: %s (%s)\n", searchWord, lemmaDisplay, r.Morphology)
+		}
+	}
+	if *printdic {
+		for _, r := range results {
+			entries, err := lexicon.Lookup(*lsjPath, r.Lemma, lsjIndex, seenLSJEntries, !*isLatin)
+			for _, e := range entries {
+				fmt.Printf("\n[ENTRY: %s]\n", e.Headword)
+				fmt.Printf("%s\n", e.Text())
+			}
+			if err != nil {
+				fmt.Println(err)
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// Lemma lists the known inflections of a lemma.
+func Lemma(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ExitOnError)
+	fPath := fs.String("f", cfg.File("greek-lemmata"), "file path for greek-lemmata.txt")
+	word := fs.String("w", "", "word")
+	isLatin := fs.Bool("l", false, "Search for latin words")
+	fs.Parse(args[1:])
+
+	if *word == "" && fs.NArg() > 0 {
+		*word = fs.Arg(0)
+	}
+	if *isLatin && !isSet(fs, "f") {
+		*fPath = cfg.File("latin-lemmata")
+	}
+
+	searchWord := morph.BetaQuery(*word)
+
+	info, err := morph.FindForms(*fPath, searchWord)
+	if err != nil {
+		fmt.Println(err)
+		return nil
+	}
+
+	if !*isLatin {
+		fmt.Printf("Lemma: %s\n", tlgcore.ToGreek(info.Lemma))
+	} else {
+		fmt.Printf("Lemma: %s\n", info.Lemma)
+	}
+	fmt.Println("Known inflections and variants:")
+	for _, f := range info.ParsedForms() {
+		if !*isLatin {
+			fmt.Printf(" - %s %s\n", tlgcore.ToGreek(f.Form), f.Analysis)
+		} else {
+			fmt.Printf(" - %s %s\n", f.Form, f.Analysis)
+		}
+	}
+	return nil
+}
+
+// Index builds a dictionary index, or with -corpus the word index of a
+// corpus. Without -f it indexes both LSJ and Lewis & Short.
+func Index(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ExitOnError)
+	xDefault, iDefault := "", ""
+	if cfg.legacy {
+		xDefault, iDefault = "grc.lsj.xml", "lsj.idt"
+	}
+	xPath := fs.String("f", xDefault, "file path for dictionary xml file (default: LSJ and L-S)")
+	iPath := fs.String("o", iDefault, "file path for export index file (default: lsj.idt or ls.idt beside it)")
+	cPath := fs.String("corpus", "", "TLG/PHI directory to index instead of a dictionary")
+	ixPath := fs.String("index", "corpus.idx", "index directory for -corpus")
+	workers := fs.Int("j", 0, "number of files indexed in parallel (0: all CPUs)")
+	fs.Parse(args[1:])
+
+	if *cPath != "" {
+		fmt.Println("Indexing", *cPath, "... this may take a while.")
+		if err := tlgindex.Build(*cPath, *ixPath, *workers, func(format string, args ...any) {
+			fmt.Printf(format, args...)
+		}); err != nil {
+			return err
+		}
+		fmt.Println("Done!", *ixPath, "updated.")
+		return nil
+	}
+
+	type job struct{ xml, idt string }
+	var jobs []job
+	switch {
+	case *xPath != "":
+		out := *iPath
+		if out == "" {
+			out = filepath.Join(filepath.Dir(*xPath), "lsj.idt")
+			if filepath.Base(*xPath) == filepath.Base(cfg.File("ls")) {
+				out = filepath.Join(filepath.Dir(*xPath), "ls.idt")
+			}
+		}
+		jobs = append(jobs, job{*xPath, out})
+	default:
+		jobs = append(jobs, job{cfg.File("lsj"), cfg.File("lsj-idt")})
+		if _, err := os.Stat(cfg.File("ls")); err == nil {
+			jobs = append(jobs, job{cfg.File("ls"), cfg.File("ls-idt")})
+		}
+	}
+
+	for _, j := range jobs {
+		fmt.Println("Indexing", j.xml, "... this may take a few seconds.")
+		if err := lexicon.BuildIndex(j.xml, j.idt); err != nil {
+			return err
+		}
+		fmt.Println("Done!", j.idt, "created.")
+	}
+	return nil
+}
+
+// Serve runs the HTTP server of package server.
+func Serve(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ExitOnError)
+	dirs := fs.String("d", strings.Join(cfg.Corpus(), ","), "corpus directories, comma separated (e.g. TLG-E,PHI-5)")
+	addr := fs.String("addr", cfg.Addr, "listen address")
+	analPath := fs.String("a", cfg.File("greek-analyses"), "analyses txt file")
+	idtPath := fs.String("idt", cfg.File("greek-analyses-idt"), "idt file")
+	latAnalPath := fs.String("la", cfg.File("latin-analyses"), "Latin analyses txt file")
+	latIdtPath := fs.String("lidt", cfg.File("latin-analyses-idt"), "Latin idt file")
+	lsjPath := fs.String("dic", cfg.File("lsj"), "LSJ XML path")
+	lsjidtPath := fs.String("dicidt", cfg.File("lsj-idt"), "LSJ idt file")
+	lsPath := fs.String("ldic", cfg.File("ls"), "Lewis & Short XML path")
+	lsidtPath := fs.String("ldicidt", cfg.File("ls-idt"), "Lewis & Short idt file")
+	fs.Parse(args[1:])
+
+	if *dirs == "" {
+		*dirs = "."
+	}
+	srv, err := server.New(server.Config{
+		Corpus:           strings.Split(*dirs, ","),
+		Analyses:         *analPath,
+		AnalysesIDT:      *idtPath,
+		LatinAnalyses:    *latAnalPath,
+		LatinAnalysesIDT: *latIdtPath,
+		LSJ:              *lsjPath,
+		LSJIDT:           *lsjidtPath,
+		LS:               *lsPath,
+		LSIDT:            *lsidtPath,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("serving on http://%s/\n", *addr)
+	return http.ListenAndServe(*addr, srv)
+}
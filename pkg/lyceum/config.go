@@ -0,0 +1,262 @@
+// Package lyceum implements the subcommands of the lyceum command and
+// finds the corpora and dependency files they use.
+package lyceum
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"tlgread/pkg/lexicon"
+)
+
+// Config holds the locations of the corpora and the dependency files.
+// Values are discovered first, then read from the config file, then
+// taken from LYCEUM_* environment variables; later sources win.
+type Config struct {
+	TLG   string            // TLG-E directory
+	PHI   string            // PHI-5 directory
+	Deps  string            // directory of greek-analyses.txt, grc.lsj.xml, ...
+	Addr  string            // listen address of serve
+	Files map[string]string // dependency files set explicitly, by key
+	Path  string            // config file that was read, if any
+
+	legacy bool // defaults of the old single-purpose binaries
+}
+
+// depFiles are the dependency files by config key.
+var depFiles = map[string]string{
+	"greek-analyses":     "greek-analyses.txt",
+	"greek-analyses-idt": "greek-analyses.idt",
+	"greek-lemmata":      "greek-lemmata.txt",
+	"lsj":                "grc.lsj.xml",
+	"lsj-idt":            "lsj.idt",
+	"latin-analyses":     "latin-analyses.txt",
+	"latin-analyses-idt": "latin-analyses.idt",
+	"latin-lemmata":      "latin-lemmata.txt",
+	"ls":                 "lat.ls.perseus-eng1.xml",
+	"ls-idt":             "ls.idt",
+	"font":               "GentiumPlus-Regular.ttf",
+}
+
+// ConfigPath returns the config file: $LYCEUM_CONFIG,
+// $home/lib/lyceum/config on Plan 9, or lyceum/config in the user's
+// config directory.
+func ConfigPath() string {
+	if p := os.Getenv("LYCEUM_CONFIG"); p != "" {
+		return p
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if p := filepath.Join(home, "lib", "lyceum", "config"); isFile(p) {
+			return p
+		}
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "lyceum", "config")
+	}
+	return ""
+}
+
+func isFile(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && !fi.IsDir()
+}
+
+// searchDirs returns the directories in which corpora and dependencies
+// are looked for: the current directory, the source tree the binary
+// was built in (bin/..), the home directory and the install locations.
+func searchDirs() []string {
+	dirs := []string{"."}
+	if exe, err := os.Executable(); err == nil {
+		dirs = append(dirs, filepath.Dir(filepath.Dir(exe)))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, "TLG"), home, filepath.Join(home, "lib", "tlg"))
+	}
+	return append(dirs, "/sys/lib/lyceum", "/usr/local/share/lyceum")
+}
+
+// discoverCorpus finds a corpus directory, e.g. TLG-E, by its authtab.dir.
+func discoverCorpus(names ...string) string {
+	for _, d := range searchDirs() {
+		for _, n := range names {
+			for _, p := range []string{filepath.Join(d, n), filepath.Join(d, "TLG", n)} {
+				if isFile(filepath.Join(p, "authtab.dir")) {
+					return p
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func discoverDeps() string {
+	for _, d := range searchDirs() {
+		for _, p := range []string{d, filepath.Join(d, "dependencies")} {
+			if isFile(filepath.Join(p, "grc.lsj.xml")) || isFile(filepath.Join(p, "greek-analyses.txt")) {
+				return p
+			}
+		}
+	}
+	return "."
+}
+
+// Load builds the configuration.
+func Load() (*Config, error) {
+	c := &Config{
+		TLG:   discoverCorpus("TLG-E", "tlg-e", "TLG"),
+		PHI:   discoverCorpus("PHI-5", "phi-5", "PHI"),
+		Deps:  discoverDeps(),
+		Addr:  "localhost:8080",
+		Files: make(map[string]string),
+	}
+
+	if p := ConfigPath(); p != "" {
+		f, err := os.Open(p)
+		if err == nil {
+			err = c.read(f)
+			f.Close()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", p, err)
+			}
+			c.Path = p
+		} else if os.Getenv("LYCEUM_CONFIG") != "" {
+			return nil, err
+		}
+	}
+
+	for key, field := range map[string]*string{
+		"LYCEUM_TLG":  &c.TLG,
+		"LYCEUM_PHI":  &c.PHI,
+		"LYCEUM_DEPS": &c.Deps,
+		"LYCEUM_ADDR": &c.Addr,
+	} {
+		if v := os.Getenv(key); v != "" {
+			*field = v
+		}
+	}
+	return c, nil
+}
+
+// Legacy returns the configuration of the old single-purpose binaries
+// (readauth, tlgviewer, search, ...): the corpus and the dependency files
+// are in the current directory, and nothing is discovered or read from
+// the config file or the environment.
+func Legacy() *Config {
+	return &Config{
+		TLG:    ".",
+		PHI:    ".",
+		Deps:   ".",
+		Addr:   "localhost:8080",
+		Files:  make(map[string]string),
+		legacy: true,
+	}
+}
+
+// read parses "key = value" lines; # starts a comment.
+func (c *Config) read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("line %d: missing =", n)
+		}
+		key, val = strings.TrimSpace(key), expandHome(strings.TrimSpace(val))
+		switch key {
+		case "tlg":
+			c.TLG = val
+		case "phi":
+			c.PHI = val
+		case "dependencies":
+			c.Deps = val
+		case "addr":
+			c.Addr = val
+		default:
+			if _, ok := depFiles[key]; !ok {
+				return fmt.Errorf("line %d: unknown key %q", n, key)
+			}
+			c.Files[key] = val
+		}
+	}
+	return scanner.Err()
+}
+
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, p[1:])
+		}
+	}
+	return p
+}
+
+// File returns the path of a dependency file by key, e.g. "lsj".
+func (c *Config) File(key string) string {
+	if p, ok := c.Files[key]; ok {
+		return p
+	}
+	return filepath.Join(c.Deps, depFiles[key])
+}
+
+// Corpus returns the configured corpus directories.
+func (c *Config) Corpus() []string {
+	var dirs []string
+	for _, d := range []string{c.TLG, c.PHI} {
+		if d != "" && !slices.Contains(dirs, d) {
+			dirs = append(dirs, d)
+		}
+	}
+	return dirs
+}
+
+// Text returns the text file of an author given as tlg0012, TLG0012,
+// lat0474 or a bare number (a TLG author).
+func (c *Config) Text(id string) (string, error) {
+	name := strings.ToLower(id)
+	if len(name) > 0 && name[0] >= '0' && name[0] <= '9' {
+		name = fmt.Sprintf("tlg%04s", name)
+	}
+	name = strings.TrimSuffix(name, ".txt") + ".txt"
+	for _, d := range c.Corpus() {
+		if p := filepath.Join(d, name); isFile(p) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("%s not found in %s", name, strings.Join(c.Corpus(), ", "))
+}
+
+// Dictionary opens the Greek or Latin analyses and dictionary.
+func (c *Config) Dictionary(latin bool) *lexicon.Dictionary {
+	if latin {
+		return lexicon.OpenDictionary(c.File("latin-analyses"), c.File("latin-analyses-idt"), c.File("ls"), c.File("ls-idt"), true)
+	}
+	return lexicon.OpenDictionary(c.File("greek-analyses"), c.File("greek-analyses-idt"), c.File("lsj"), c.File("lsj-idt"), false)
+}
+
+// WriteTo writes the configuration in the config file format.
+func (c *Config) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	if c.Path != "" {
+		fmt.Fprintf(&b, "# read from %s\n", c.Path)
+	}
+	fmt.Fprintf(&b, "tlg = %s\nphi = %s\ndependencies = %s\naddr = %s\n", c.TLG, c.PHI, c.Deps, c.Addr)
+	keys := make([]string, 0, len(depFiles))
+	for k := range depFiles {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s = %s\n", k, c.File(k))
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
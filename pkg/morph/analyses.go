@@ -0,0 +1,239 @@
+package morph
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"tlgread/pkg/tlgcore"
+)
+
+type MorphResult struct {
+	Form       string
+	Lemma      string
+	ShortDef   string
+	Morphology string
+}
+
+func LoadIndex(idtPath string) (map[string]int64, []string, error) {
+	index := make(map[string]int64)
+	var keys []string
+	file, err := os.Open(idtPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	re := regexp.MustCompile(`'(.+?)' => (\d+)`)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		matches := re.FindStringSubmatch(scanner.Text())
+		if len(matches) == 3 {
+			offset, _ := strconv.ParseInt(matches[2], 10, 64)
+			key := matches[1]
+			index[key] = offset
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return index, keys, nil
+}
+
+// analysisRe matches one analysis of a form in an analyses file:
+// {id n lemma  definition  morphology}.
+var (
+	analysisRe = regexp.MustCompile(`\{[^ ]+ \d+ (?:[^,]+,)?(?P<lemma>[^ ]+)(?P<content>.*?)\}`)
+	columnRe   = regexp.MustCompile(`\s{2,}`)
+)
+
+// parseAnalysis turns a match of analysisRe into a MorphResult.
+func parseAnalysis(form string, match []string) MorphResult {
+	parts := columnRe.Split(strings.TrimSpace(match[2]), -1)
+
+	resDef := "---"
+	resMorph := ""
+	if len(parts) >= 2 {
+		resDef = strings.TrimSpace(parts[0])
+		resMorph = strings.TrimSpace(parts[1])
+	} else if len(parts) == 1 {
+		resMorph = strings.TrimSpace(parts[0])
+	}
+
+	return MorphResult{
+		Form:       form,
+		Lemma:      strings.TrimSpace(match[1]), // Final fix for trailing spaces
+		ShortDef:   resDef,
+		Morphology: resMorph,
+	}
+}
+
+func FindLemmaIndexed(filePath string, offset int64, searchForm string) ([]MorphResult, error) {
+	if searchForm == "" {
+		return nil, fmt.Errorf("not found")
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	file.Seek(offset, 0)
+	scanner := bufio.NewScanner(file)
+	buf := make([]byte, 1024*1024)
+	scanner.Buffer(buf, 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		currentWord := strings.TrimPrefix(fields[0], "!")
+
+		if strings.EqualFold(currentWord, searchForm) {
+			var results []MorphResult
+			for _, match := range analysisRe.FindAllStringSubmatch(line, -1) {
+				results = append(results, parseAnalysis(searchForm, match))
+			}
+			return results, nil
+		}
+		if len(currentWord) > 0 && currentWord[0] > searchForm[0] {
+			break
+		}
+	}
+	return nil, fmt.Errorf("not found")
+}
+
+// Analyses is an analyses file (greek-analyses.txt) with its index.
+type Analyses struct {
+	Path  string
+	index map[string]int64
+	keys  []string
+}
+
+func OpenAnalyses(analPath, idtPath string) (*Analyses, error) {
+	if _, err := os.Stat(analPath); err != nil {
+		return nil, err
+	}
+	index, keys, err := LoadIndex(idtPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Analyses{Path: analPath, index: index, keys: keys}, nil
+}
+
+// Lookup returns the analyses of a normalized Beta Code form.
+func (a *Analyses) Lookup(searchWord string) ([]MorphResult, error) {
+	idx := sort.SearchStrings(a.keys, searchWord)
+	if idx > 0 {
+		idx -= 1
+	}
+
+	var results []MorphResult
+	err := fmt.Errorf("not found")
+	for i := range 3 {
+		if idx-i < 0 || idx-i >= len(a.keys) {
+			break
+		}
+		results, err = FindLemmaIndexed(a.Path, a.index[a.keys[idx-i]], searchWord)
+		if err == nil {
+			break
+		}
+	}
+	return results, err
+}
+
+// Forms returns every form of a lemma (normalized Beta Code) in the
+// analyses file, with its analyses. The whole file is read.
+func (a *Analyses) Forms(lemma string) ([]MorphResult, error) {
+	file, err := os.Open(a.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+
+	var results []MorphResult
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.Contains(line, lemma) {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		form := strings.TrimPrefix(fields[0], "!")
+		for _, match := range analysisRe.FindAllStringSubmatch(line, -1) {
+			if r := parseAnalysis(form, match); r.Lemma == lemma {
+				results = append(results, r)
+			}
+		}
+	}
+	return results, scanner.Err()
+}
+
+// LookupWord looks up a word as returned by tlgcore.Words. Since those
+// are lower case, a capitalized form is tried if the word is not found.
+func (a *Analyses) LookupWord(word string) ([]MorphResult, error) {
+	form := tlgcore.NormalizeBetaCode(word)
+	results, err := a.Lookup(form)
+	if err != nil {
+		results, err = a.Lookup(capitalized(form))
+	}
+	return results, err
+}
+
+// capitalized returns a lower case Beta Code word as a capitalized one.
+// The diacritics of the first letter go before it, after the '*':
+// "a)xilleu/s" becomes "*)axilleu/s".
+func capitalized(form string) string {
+	if form == "" {
+		return form
+	}
+	i := 1
+	for i < len(form) && strings.ContainsRune(`)(/\=+|`, rune(form[i])) {
+		i++
+	}
+	return "*" + form[1:i] + form[:1] + form[i:]
+}
+
+// Lemmata returns the distinct lemmata a form may belong to.
+func (a *Analyses) Lemmata(form string) []string {
+	results, err := a.LookupWord(form)
+	if err != nil {
+		return nil
+	}
+
+	var lemmata []string
+	seen := make(map[string]bool)
+	for _, r := range results {
+		fields := strings.Fields(r.Lemma)
+		if len(fields) == 0 || seen[fields[0]] {
+			continue
+		}
+		seen[fields[0]] = true
+		lemmata = append(lemmata, fields[0])
+	}
+	return lemmata
+}
+
+// BetaQuery converts a search word given in Greek or Beta Code into
+// the normalized Beta Code used by the morphology files.
+func BetaQuery(word string) string {
+	searchWord := word
+	for _, r := range word {
+		if r > 127 { // Simple check for non-ASCII
+			searchWord = tlgcore.ToBetaCode(word)
+			break
+		}
+	}
+	return tlgcore.NormalizeBetaCode(searchWord)
+}
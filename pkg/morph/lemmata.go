@@ -0,0 +1,110 @@
+// Package morph reads the diogenes morphology data: greek-lemmata.txt
+// (lemma to inflected forms) and greek-analyses.txt (form to lemmata
+// and parses), and their Latin counterparts.
+package morph
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"tlgread/pkg/tlgcore"
+)
+
+type LemmaInfo struct {
+	Lemma string
+	Forms []string
+}
+
+// Form is one inflected form of a lemma with its analysis,
+// e.g. "e)/legon" "imperf ind act 1st sg".
+type Form struct {
+	Form     string
+	Analysis string
+}
+
+func FindForms(filePath, targetLemma string) (*LemmaInfo, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		parts := strings.Split(line, "\t")
+
+		if len(parts) < 3 {
+			continue
+		}
+
+		lemma := strings.TrimSpace(parts[0])
+		if lemma == targetLemma {
+			allForms := parts[2:]
+			return &LemmaInfo{
+				Lemma: lemma,
+				Forms: allForms,
+			}, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("lemma %s not found", targetLemma)
+}
+
+// ParsedForms splits the raw form entries into forms and analyses.
+func (info *LemmaInfo) ParsedForms() []Form {
+	var forms []Form
+	for _, f := range info.Forms {
+		if f == "" {
+			continue
+		}
+		form := strings.Split(f, " ")
+		forms = append(forms, Form{
+			Form:     form[0],
+			Analysis: strings.TrimSpace(strings.Join(form[1:], " ")),
+		})
+	}
+	return forms
+}
+
+// FormKeys returns the forms of a lemma (Greek or Beta Code) keyed as
+// tlgcore.Words and tlgcore.WordKey key the words of the texts, each
+// with its analyses. The forms listed in the lemmata file are completed
+// with those of the analyses file, if analyses is not nil.
+func FormKeys(lemmataPath string, analyses *Analyses, lemma string, strict bool) (map[string][]string, error) {
+	query := BetaQuery(lemma)
+	forms := make(map[string][]string)
+
+	info, err := FindForms(lemmataPath, query)
+	if err == nil {
+		for _, f := range info.ParsedForms() {
+			if key := tlgcore.QueryKey(f.Form, strict); key != "" {
+				forms[key] = append(forms[key], f.Analysis)
+			}
+		}
+	}
+	if analyses != nil {
+		results, aerr := analyses.Forms(query)
+		if aerr != nil {
+			return nil, aerr
+		}
+		for _, r := range results {
+			key := tlgcore.QueryKey(r.Form, strict)
+			if key != "" && !slices.Contains(forms[key], r.Morphology) {
+				forms[key] = append(forms[key], r.Morphology)
+			}
+		}
+	}
+	if len(forms) == 0 && err != nil {
+		return nil, err
+	}
+	return forms, nil
+}
@@ -0,0 +1,339 @@
+package ninep
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Error is an Rerror from the server.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Client is a 9P connection. Its methods may be called from several
+// goroutines; requests are multiplexed by tag.
+type Client struct {
+	rwc   io.ReadWriteCloser
+	msize uint32
+
+	wmu sync.Mutex // serializes writes
+
+	mu      sync.Mutex
+	tags    map[uint16]chan *Fcall
+	nextTag uint16
+	nextFid uint32
+	freeFid []uint32
+	err     error
+}
+
+// Dial connects to a 9P server, e.g. Dial("tcp", "localhost:5640").
+func Dial(network, addr string) (*Client, error) {
+	c, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(c)
+}
+
+// NewClient negotiates the protocol version on a connection.
+func NewClient(rwc io.ReadWriteCloser) (*Client, error) {
+	c := &Client{rwc: rwc, msize: 64 * 1024, tags: make(map[uint16]chan *Fcall)}
+
+	t := &Fcall{Type: Tversion, Tag: NOTAG, Msize: c.msize, Version: Version}
+	if err := WriteFcall(rwc, t); err != nil {
+		rwc.Close()
+		return nil, err
+	}
+	r, err := ReadFcall(rwc, c.msize)
+	if err != nil {
+		rwc.Close()
+		return nil, err
+	}
+	if r.Type != Rversion || r.Version != Version {
+		rwc.Close()
+		return nil, fmt.Errorf("ninep: server speaks %q", r.Version)
+	}
+	c.msize = min(c.msize, r.Msize)
+
+	go c.readLoop()
+	return c, nil
+}
+
+func (c *Client) readLoop() {
+	for {
+		r, err := ReadFcall(c.rwc, c.msize)
+		c.mu.Lock()
+		if err != nil {
+			c.err = err
+			for tag, ch := range c.tags {
+				close(ch)
+				delete(c.tags, tag)
+			}
+			c.mu.Unlock()
+			return
+		}
+		ch, ok := c.tags[r.Tag]
+		delete(c.tags, r.Tag)
+		c.mu.Unlock()
+		if ok {
+			ch <- r
+		}
+	}
+}
+
+func (c *Client) Close() error {
+	return c.rwc.Close()
+}
+
+// rpc sends a request and waits for its reply.
+func (c *Client) rpc(t *Fcall) (*Fcall, error) {
+	ch := make(chan *Fcall, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	for {
+		c.nextTag++
+		if c.nextTag == NOTAG {
+			c.nextTag = 0
+		}
+		if _, busy := c.tags[c.nextTag]; !busy {
+			break
+		}
+	}
+	t.Tag = c.nextTag
+	c.tags[t.Tag] = ch
+	c.mu.Unlock()
+
+	c.wmu.Lock()
+	err := WriteFcall(c.rwc, t)
+	c.wmu.Unlock()
+	if err != nil {
+		c.mu.Lock()
+		delete(c.tags, t.Tag)
+		c.mu.Unlock()
+		return nil, err
+	}
+
+	r, ok := <-ch
+	if !ok {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return nil, c.err
+	}
+	if r.Type == Rerror {
+		return nil, Error(r.Ename)
+	}
+	if r.Type != t.Type+1 {
+		return nil, fmt.Errorf("ninep: reply type %d to request %d", r.Type, t.Type)
+	}
+	return r, nil
+}
+
+func (c *Client) newFid() *Fid {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var n uint32
+	if len(c.freeFid) > 0 {
+		n = c.freeFid[len(c.freeFid)-1]
+		c.freeFid = c.freeFid[:len(c.freeFid)-1]
+	} else {
+		n = c.nextFid
+		c.nextFid++
+	}
+	return &Fid{c: c, fid: n}
+}
+
+func (c *Client) releaseFid(n uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.freeFid = append(c.freeFid, n)
+}
+
+// Attach returns the root of the server's tree.
+func (c *Client) Attach(uname, aname string) (*Fid, error) {
+	f := c.newFid()
+	r, err := c.rpc(&Fcall{Type: Tattach, Fid: f.fid, Afid: NOFID, Uname: uname, Aname: aname})
+	if err != nil {
+		c.releaseFid(f.fid)
+		return nil, err
+	}
+	f.qid = r.Qid
+	return f, nil
+}
+
+// Fid is a file on the server. After Open it can be used as an
+// io.ReadWriter at a running offset.
+type Fid struct {
+	c      *Client
+	fid    uint32
+	qid    Qid
+	mode   uint8
+	iounit uint32
+
+	mu     sync.Mutex
+	offset int64
+}
+
+func (f *Fid) Qid() Qid { return f.qid }
+
+// Walk returns a new fid for a slash-separated path relative to f.
+func (f *Fid) Walk(path string) (*Fid, error) {
+	var names []string
+	for _, n := range strings.Split(path, "/") {
+		if n != "" && n != "." {
+			names = append(names, n)
+		}
+	}
+
+	nf := f.c.newFid()
+	from := f.fid
+	nf.qid = f.qid
+	for first := true; first || len(names) > 0; first = false {
+		n := min(len(names), MAXWELEM)
+		r, err := f.c.rpc(&Fcall{Type: Twalk, Fid: from, Newfid: nf.fid, Wname: names[:n]})
+		if err == nil && len(r.Wqid) < n {
+			err = fmt.Errorf("%s: %w", names[len(r.Wqid)], ErrNotFound)
+		}
+		if err != nil {
+			if from == nf.fid {
+				nf.Close()
+			} else {
+				f.c.releaseFid(nf.fid)
+			}
+			return nil, err
+		}
+		if n > 0 {
+			nf.qid = r.Wqid[n-1]
+		}
+		names = names[n:]
+		from = nf.fid
+	}
+	return nf, nil
+}
+
+// Open opens the file with a mode such as OREAD or ORDWR.
+func (f *Fid) Open(mode uint8) error {
+	r, err := f.c.rpc(&Fcall{Type: Topen, Fid: f.fid, Mode: mode})
+	if err != nil {
+		return err
+	}
+	f.qid = r.Qid
+	f.mode = mode
+	f.iounit = r.Iounit
+	if f.iounit == 0 || f.iounit > f.c.msize-IOHDRSZ {
+		f.iounit = f.c.msize - IOHDRSZ
+	}
+	return nil
+}
+
+// Close clunks the fid.
+func (f *Fid) Close() error {
+	_, err := f.c.rpc(&Fcall{Type: Tclunk, Fid: f.fid})
+	f.c.releaseFid(f.fid)
+	return err
+}
+
+func (f *Fid) ReadAt(p []byte, off int64) (int, error) {
+	n := min(uint32(len(p)), f.iounit)
+	r, err := f.c.rpc(&Fcall{Type: Tread, Fid: f.fid, Offset: uint64(off), Count: n})
+	if err != nil {
+		return 0, err
+	}
+	if len(r.Data) == 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
+	return copy(p, r.Data), nil
+}
+
+func (f *Fid) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, err := f.ReadAt(p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *Fid) WriteAt(p []byte, off int64) (int, error) {
+	total := 0
+	for len(p) > 0 || total == 0 {
+		n := min(uint32(len(p)), f.iounit)
+		r, err := f.c.rpc(&Fcall{Type: Twrite, Fid: f.fid, Offset: uint64(off), Data: p[:n]})
+		if err != nil {
+			return total, err
+		}
+		total += int(r.Count)
+		off += int64(r.Count)
+		p = p[r.Count:]
+		if r.Count == 0 {
+			return total, io.ErrShortWrite
+		}
+		if len(p) == 0 {
+			break
+		}
+	}
+	return total, nil
+}
+
+func (f *Fid) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, err := f.WriteAt(p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+// Stat returns the directory entry of the file.
+func (f *Fid) Stat() (*Dir, error) {
+	r, err := f.c.rpc(&Fcall{Type: Tstat, Fid: f.fid})
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalDir(r.Stat)
+}
+
+// Dirreadall reads all entries of an open directory.
+func (f *Fid) Dirreadall() ([]*Dir, error) {
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalDirs(data)
+}
+
+// OpenFile walks to a path relative to f and opens it.
+func (f *Fid) OpenFile(path string, mode uint8) (*Fid, error) {
+	nf, err := f.Walk(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := nf.Open(mode); err != nil {
+		nf.Close()
+		return nil, err
+	}
+	return nf, nil
+}
+
+// ReadFile returns the contents of a file relative to f.
+func (f *Fid) ReadFile(path string) ([]byte, error) {
+	nf, err := f.OpenFile(path, OREAD)
+	if err != nil {
+		return nil, err
+	}
+	defer nf.Close()
+	return io.ReadAll(nf)
+}
+
+// IsNotExist reports whether err says that a file does not exist.
+func IsNotExist(err error) bool {
+	if errors.Is(err, ErrNotFound) {
+		return true
+	}
+	var e Error
+	return errors.As(err, &e) && strings.Contains(string(e), "not exist")
+}
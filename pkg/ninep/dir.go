@@ -0,0 +1,100 @@
+package ninep
+
+import (
+	"fmt"
+	"time"
+)
+
+// Dir is the information returned by stat.
+type Dir struct {
+	Type   uint16
+	Dev    uint32
+	Qid    Qid
+	Mode   uint32
+	Atime  uint32
+	Mtime  uint32
+	Length uint64
+	Name   string
+	Uid    string
+	Gid    string
+	Muid   string
+}
+
+func (d *Dir) IsDir() bool {
+	return d.Mode&DMDIR != 0
+}
+
+func (d *Dir) ModTime() time.Time {
+	return time.Unix(int64(d.Mtime), 0)
+}
+
+// Bytes encodes the directory entry, including its size field.
+func (d *Dir) Bytes() []byte {
+	b := buffer{0, 0}
+	b.u16(d.Type)
+	b.u32(d.Dev)
+	b.qid(d.Qid)
+	b.u32(d.Mode)
+	b.u32(d.Atime)
+	b.u32(d.Mtime)
+	b.u64(d.Length)
+	b.str(d.Name)
+	b.str(d.Uid)
+	b.str(d.Gid)
+	b.str(d.Muid)
+	b[0] = byte(len(b) - 2)
+	b[1] = byte((len(b) - 2) >> 8)
+	return b
+}
+
+// UnmarshalDir decodes one directory entry.
+func UnmarshalDir(b []byte) (*Dir, error) {
+	r := &reader{b: b}
+	if n := r.u16(); int(n) != len(b)-2 {
+		return nil, ErrBadMessage
+	}
+	d := &Dir{
+		Type:   r.u16(),
+		Dev:    r.u32(),
+		Qid:    r.qid(),
+		Mode:   r.u32(),
+		Atime:  r.u32(),
+		Mtime:  r.u32(),
+		Length: r.u64(),
+		Name:   r.str(),
+		Uid:    r.str(),
+		Gid:    r.str(),
+		Muid:   r.str(),
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return d, nil
+}
+
+// UnmarshalDirs decodes the directory entries returned by reading a
+// directory.
+func UnmarshalDirs(b []byte) ([]*Dir, error) {
+	var dirs []*Dir
+	for len(b) > 0 {
+		if len(b) < 2 {
+			return dirs, ErrBadMessage
+		}
+		n := int(b[0]) | int(b[1])<<8
+		if len(b) < n+2 {
+			return dirs, ErrBadMessage
+		}
+		d, err := UnmarshalDir(b[:n+2])
+		if err != nil {
+			return dirs, err
+		}
+		dirs = append(dirs, d)
+		b = b[n+2:]
+	}
+	return dirs, nil
+}
+
+func (d *Dir) String() string {
+	return fmt.Sprintf("'%s' '%s' '%s' '%s' q %v m %#o at %d mt %d l %d",
+		d.Name, d.Uid, d.Gid, d.Muid, d.Qid, d.Mode, d.Atime, d.Mtime, d.Length)
+}
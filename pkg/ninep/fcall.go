@@ -0,0 +1,354 @@
+// Package ninep implements the 9P2000 file protocol: message encoding,
+// a server for synthetic file trees and a client.
+package ninep
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Message types.
+const (
+	Tversion = 100 + iota
+	Rversion
+	Tauth
+	Rauth
+	Tattach
+	Rattach
+	Terror // illegal
+	Rerror
+	Tflush
+	Rflush
+	Twalk
+	Rwalk
+	Topen
+	Ropen
+	Tcreate
+	Rcreate
+	Tread
+	Rread
+	Twrite
+	Rwrite
+	Tclunk
+	Rclunk
+	Tremove
+	Rremove
+	Tstat
+	Rstat
+	Twstat
+	Rwstat
+)
+
+const (
+	Version  = "9P2000"
+	NOTAG    = 0xFFFF
+	NOFID    = 0xFFFFFFFF
+	IOHDRSZ  = 24 // size of the header of Twrite/Rread
+	MAXWELEM = 16
+)
+
+// Open modes.
+const (
+	OREAD  = 0
+	OWRITE = 1
+	ORDWR  = 2
+	OEXEC  = 3
+	OTRUNC = 0x10
+)
+
+// Qid types and Dir mode bits.
+const (
+	QTDIR  = 0x80
+	QTFILE = 0x00
+	DMDIR  = 0x80000000
+)
+
+type Qid struct {
+	Type uint8
+	Vers uint32
+	Path uint64
+}
+
+func (q Qid) String() string {
+	return fmt.Sprintf("(%016x %d %02x)", q.Path, q.Vers, q.Type)
+}
+
+// Fcall is a 9P message. Only the fields of its Type are used.
+type Fcall struct {
+	Type    uint8
+	Tag     uint16
+	Fid     uint32
+	Msize   uint32   // Tversion, Rversion
+	Version string   // Tversion, Rversion
+	Oldtag  uint16   // Tflush
+	Ename   string   // Rerror
+	Qid     Qid      // Rattach, Ropen, Rcreate
+	Iounit  uint32   // Ropen, Rcreate
+	Afid    uint32   // Tauth, Tattach
+	Uname   string   // Tauth, Tattach
+	Aname   string   // Tauth, Tattach
+	Aqid    Qid      // Rauth
+	Perm    uint32   // Tcreate
+	Name    string   // Tcreate
+	Mode    uint8    // Topen, Tcreate
+	Newfid  uint32   // Twalk
+	Wname   []string // Twalk
+	Wqid    []Qid    // Rwalk
+	Offset  uint64   // Tread, Twrite
+	Count   uint32   // Tread, Rwrite
+	Data    []byte   // Twrite, Rread
+	Stat    []byte   // Rstat, Twstat
+}
+
+var ErrBadMessage = errors.New("ninep: malformed message")
+
+// buffer appends little-endian fields.
+type buffer []byte
+
+func (b *buffer) u8(v uint8)   { *b = append(*b, v) }
+func (b *buffer) u16(v uint16) { *b = binary.LittleEndian.AppendUint16(*b, v) }
+func (b *buffer) u32(v uint32) { *b = binary.LittleEndian.AppendUint32(*b, v) }
+func (b *buffer) u64(v uint64) { *b = binary.LittleEndian.AppendUint64(*b, v) }
+
+func (b *buffer) str(s string) {
+	b.u16(uint16(len(s)))
+	*b = append(*b, s...)
+}
+
+func (b *buffer) qid(q Qid) {
+	b.u8(q.Type)
+	b.u32(q.Vers)
+	b.u64(q.Path)
+}
+
+// reader consumes little-endian fields, remembering the first error.
+type reader struct {
+	b   []byte
+	err error
+}
+
+// take returns the next n bytes. Past the end of the message it sets
+// r.err and returns zeros, only enough for the fixed-size fields: n may
+// be a length sent by the peer.
+func (r *reader) take(n int) []byte {
+	if r.err != nil || len(r.b) < n {
+		r.err = ErrBadMessage
+		return make([]byte, min(n, 8))
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *reader) u8() uint8   { return r.take(1)[0] }
+func (r *reader) u16() uint16 { return binary.LittleEndian.Uint16(r.take(2)) }
+func (r *reader) u32() uint32 { return binary.LittleEndian.Uint32(r.take(4)) }
+func (r *reader) u64() uint64 { return binary.LittleEndian.Uint64(r.take(8)) }
+func (r *reader) str() string { return string(r.take(int(r.u16()))) }
+
+func (r *reader) qid() Qid {
+	return Qid{Type: r.u8(), Vers: r.u32(), Path: r.u64()}
+}
+
+// Bytes encodes the message, including its size field.
+func (f *Fcall) Bytes() ([]byte, error) {
+	b := buffer{0, 0, 0, 0}
+	b.u8(f.Type)
+	b.u16(f.Tag)
+
+	switch f.Type {
+	case Tversion, Rversion:
+		b.u32(f.Msize)
+		b.str(f.Version)
+	case Tauth:
+		b.u32(f.Afid)
+		b.str(f.Uname)
+		b.str(f.Aname)
+	case Rauth:
+		b.qid(f.Aqid)
+	case Tattach:
+		b.u32(f.Fid)
+		b.u32(f.Afid)
+		b.str(f.Uname)
+		b.str(f.Aname)
+	case Rattach:
+		b.qid(f.Qid)
+	case Rerror:
+		b.str(f.Ename)
+	case Tflush:
+		b.u16(f.Oldtag)
+	case Twalk:
+		b.u32(f.Fid)
+		b.u32(f.Newfid)
+		b.u16(uint16(len(f.Wname)))
+		for _, n := range f.Wname {
+			b.str(n)
+		}
+	case Rwalk:
+		b.u16(uint16(len(f.Wqid)))
+		for _, q := range f.Wqid {
+			b.qid(q)
+		}
+	case Topen:
+		b.u32(f.Fid)
+		b.u8(f.Mode)
+	case Ropen, Rcreate:
+		b.qid(f.Qid)
+		b.u32(f.Iounit)
+	case Tcreate:
+		b.u32(f.Fid)
+		b.str(f.Name)
+		b.u32(f.Perm)
+		b.u8(f.Mode)
+	case Tread:
+		b.u32(f.Fid)
+		b.u64(f.Offset)
+		b.u32(f.Count)
+	case Rread:
+		b.u32(uint32(len(f.Data)))
+		b = append(b, f.Data...)
+	case Twrite:
+		b.u32(f.Fid)
+		b.u64(f.Offset)
+		b.u32(uint32(len(f.Data)))
+		b = append(b, f.Data...)
+	case Rwrite:
+		b.u32(f.Count)
+	case Tclunk, Tremove, Tstat:
+		b.u32(f.Fid)
+	case Rstat:
+		b.u16(uint16(len(f.Stat)))
+		b = append(b, f.Stat...)
+	case Twstat:
+		b.u32(f.Fid)
+		b.u16(uint16(len(f.Stat)))
+		b = append(b, f.Stat...)
+	case Rflush, Rclunk, Rremove, Rwstat:
+	default:
+		return nil, fmt.Errorf("ninep: unknown message type %d", f.Type)
+	}
+
+	binary.LittleEndian.PutUint32(b, uint32(len(b)))
+	return b, nil
+}
+
+// UnmarshalFcall decodes a message, including its size field.
+func UnmarshalFcall(b []byte) (*Fcall, error) {
+	r := &reader{b: b}
+	if size := r.u32(); int(size) != len(b) {
+		return nil, ErrBadMessage
+	}
+	f := &Fcall{Type: r.u8(), Tag: r.u16()}
+	if r.err != nil {
+		return nil, ErrBadMessage
+	}
+
+	switch f.Type {
+	case Tversion, Rversion:
+		f.Msize = r.u32()
+		f.Version = r.str()
+	case Tauth:
+		f.Afid = r.u32()
+		f.Uname = r.str()
+		f.Aname = r.str()
+	case Rauth:
+		f.Aqid = r.qid()
+	case Tattach:
+		f.Fid = r.u32()
+		f.Afid = r.u32()
+		f.Uname = r.str()
+		f.Aname = r.str()
+	case Rattach:
+		f.Qid = r.qid()
+	case Rerror:
+		f.Ename = r.str()
+	case Tflush:
+		f.Oldtag = r.u16()
+	case Twalk:
+		f.Fid = r.u32()
+		f.Newfid = r.u32()
+		n := int(r.u16())
+		if n > MAXWELEM {
+			return nil, ErrBadMessage
+		}
+		for i := 0; i < n && r.err == nil; i++ {
+			f.Wname = append(f.Wname, r.str())
+		}
+	case Rwalk:
+		n := int(r.u16())
+		if n > MAXWELEM {
+			return nil, ErrBadMessage
+		}
+		for i := 0; i < n && r.err == nil; i++ {
+			f.Wqid = append(f.Wqid, r.qid())
+		}
+	case Topen:
+		f.Fid = r.u32()
+		f.Mode = r.u8()
+	case Ropen, Rcreate:
+		f.Qid = r.qid()
+		f.Iounit = r.u32()
+	case Tcreate:
+		f.Fid = r.u32()
+		f.Name = r.str()
+		f.Perm = r.u32()
+		f.Mode = r.u8()
+	case Tread:
+		f.Fid = r.u32()
+		f.Offset = r.u64()
+		f.Count = r.u32()
+	case Rread:
+		f.Data = r.take(int(r.u32()))
+	case Twrite:
+		f.Fid = r.u32()
+		f.Offset = r.u64()
+		f.Data = r.take(int(r.u32()))
+	case Rwrite:
+		f.Count = r.u32()
+	case Tclunk, Tremove, Tstat:
+		f.Fid = r.u32()
+	case Rstat:
+		f.Stat = r.take(int(r.u16()))
+	case Twstat:
+		f.Fid = r.u32()
+		f.Stat = r.take(int(r.u16()))
+	case Rflush, Rclunk, Rremove, Rwstat:
+	default:
+		return nil, fmt.Errorf("ninep: unknown message type %d", f.Type)
+	}
+
+	if r.err != nil || len(r.b) != 0 {
+		return nil, ErrBadMessage
+	}
+	return f, nil
+}
+
+// ReadFcall reads one message. Messages longer than msize are errors.
+func ReadFcall(rd io.Reader, msize uint32) (*Fcall, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(rd, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.LittleEndian.Uint32(size[:])
+	if n < 7 || n > msize {
+		return nil, ErrBadMessage
+	}
+	b := make([]byte, n)
+	copy(b, size[:])
+	if _, err := io.ReadFull(rd, b[4:]); err != nil {
+		return nil, err
+	}
+	return UnmarshalFcall(b)
+}
+
+// WriteFcall writes one message.
+func WriteFcall(w io.Writer, f *Fcall) error {
+	b, err := f.Bytes()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
@@ -0,0 +1,558 @@
+package ninep
+
+import (
+	"errors"
+	"hash/fnv"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Node is a file or directory of a synthetic file tree. A Node must
+// also implement Directory or File.
+type Node interface {
+	Name() string
+}
+
+// Directory is a Node that can be walked and listed.
+type Directory interface {
+	Node
+	Walk(s *Session, name string) (Node, error)
+	List(s *Session) ([]Node, error)
+}
+
+// File is a Node that can be opened.
+type File interface {
+	Node
+	Open(s *Session, mode uint8) (Handle, error)
+	Writable() bool
+}
+
+// Handle is an open file.
+type Handle interface {
+	io.ReaderAt
+	io.WriterAt
+	io.Closer
+}
+
+var (
+	ErrNotFound   = errors.New("file does not exist")
+	ErrPerm       = errors.New("permission denied")
+	ErrNotDir     = errors.New("not a directory")
+	ErrIsDir      = errors.New("is a directory")
+	ErrBadFid     = errors.New("unknown fid")
+	ErrFidInUse   = errors.New("fid already in use")
+	ErrNotOpen    = errors.New("fid not open")
+	ErrOpen       = errors.New("fid already open")
+	ErrNoAuth     = errors.New("authentication not required")
+	ErrBadVersion = errors.New("version not negotiated")
+	ErrMsize      = errors.New("message size too small")
+
+	// errFlushed is the result of a request aborted by Tflush or
+	// Tversion; it is never sent.
+	errFlushed = errors.New("request flushed")
+)
+
+// minMsize is the smallest message size a client may ask for.
+const minMsize = 256
+
+// Session is the state of one client connection. Files can keep
+// per-connection settings in it, so that clients do not disturb each
+// other.
+type Session struct {
+	Uname string
+	mu    sync.Mutex
+	vals  map[string]string
+}
+
+func (s *Session) Get(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.vals[key]
+}
+
+func (s *Session) Set(key, val string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.vals == nil {
+		s.vals = make(map[string]string)
+	}
+	s.vals[key] = val
+}
+
+// Server serves a file tree to 9P clients.
+type Server struct {
+	Root  Directory
+	Msize uint32 // maximum message size, 64 KB if zero
+	Debug bool   // log every message
+
+	once  sync.Once
+	start uint32 // time the server started, for Dir.Atime and Mtime
+}
+
+type fid struct {
+	path   []Node // nodes from the root to this fid
+	handle Handle
+	dir    []byte // directory contents while open
+	open   bool
+}
+
+func (f *fid) node() Node {
+	return f.path[len(f.path)-1]
+}
+
+type conn struct {
+	srv     *Server
+	rwc     io.ReadWriteCloser
+	msize   uint32
+	session *Session
+
+	wmu sync.Mutex // serializes replies
+
+	mu       sync.Mutex
+	fids     map[uint32]*fid
+	inflight map[uint16]*Fcall // requests not yet answered nor aborted
+}
+
+// ListenAndServe serves the tree on a network address, e.g. "tcp",
+// "localhost:5640" or "unix", "/tmp/ns.glenda.:0/tlg".
+func (srv *Server) ListenAndServe(network, addr string) error {
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go srv.Serve(c)
+	}
+}
+
+// Serve answers the requests on one connection until it is closed.
+// Each request is handled in its own goroutine, so a slow or blocking
+// file does not hold up the others.
+func (srv *Server) Serve(rwc io.ReadWriteCloser) error {
+	defer rwc.Close()
+	srv.once.Do(func() {
+		srv.start = uint32(time.Now().Unix())
+	})
+	c := &conn{
+		srv:      srv,
+		rwc:      rwc,
+		msize:    srv.Msize,
+		session:  &Session{},
+		fids:     make(map[uint32]*fid),
+		inflight: make(map[uint16]*Fcall),
+	}
+	if c.msize == 0 {
+		c.msize = 64 * 1024
+	}
+
+	// Requests still running when the connection ends are aborted,
+	// not waited for.
+	defer c.clunkAll()
+	defer c.abort()
+
+	versioned := false
+	for {
+		t, err := ReadFcall(rwc, c.msize)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if srv.Debug {
+			log.Printf("<- %d tag %d fid %d", t.Type, t.Tag, t.Fid)
+		}
+
+		switch {
+		case t.Type == Tversion:
+			// Tversion aborts all outstanding requests: they are
+			// not waited for, and their replies are dropped.
+			c.abort()
+			r, err := c.version(t)
+			versioned = err == nil && r.Version == Version
+			c.send(t, r, err)
+		case !versioned:
+			c.send(t, nil, ErrBadVersion)
+		default:
+			c.mu.Lock()
+			c.inflight[t.Tag] = t
+			c.mu.Unlock()
+
+			go func() {
+				r, err := c.handle(t)
+				c.reply(t, r, err)
+			}()
+		}
+	}
+}
+
+// reply answers a request unless it has been aborted. The check is
+// made under wmu, so that an Rflush cannot overtake the reply to the
+// request it flushes.
+func (c *conn) reply(t, r *Fcall, err error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.mu.Lock()
+	current := c.current(t)
+	if current {
+		delete(c.inflight, t.Tag)
+	}
+	c.mu.Unlock()
+	if current {
+		c.respond(t, r, err)
+	}
+}
+
+// send answers a request that was not registered as in flight.
+func (c *conn) send(t, r *Fcall, err error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.respond(t, r, err)
+}
+
+// respond sends a reply; c.wmu must be held.
+func (c *conn) respond(t, r *Fcall, err error) {
+	if err != nil {
+		r = &Fcall{Type: Rerror, Ename: err.Error()}
+	}
+	r.Tag = t.Tag
+	if err := WriteFcall(c.rwc, r); err != nil {
+		c.rwc.Close()
+	}
+}
+
+// abort drops all outstanding requests.
+func (c *conn) abort() {
+	c.mu.Lock()
+	clear(c.inflight)
+	c.mu.Unlock()
+}
+
+// current reports whether a request is still to be answered; c.mu must
+// be held. Requests that are not current must not change the fids.
+func (c *conn) current(t *Fcall) bool {
+	return c.inflight[t.Tag] == t
+}
+
+// getFid returns a fid and a copy of its state.
+func (c *conn) getFid(n uint32) (*fid, fid, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, ok := c.fids[n]
+	if !ok {
+		return nil, fid{}, false
+	}
+	return f, *f, true
+}
+
+func (c *conn) clunkAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for n, f := range c.fids {
+		if f.handle != nil {
+			f.handle.Close()
+		}
+		delete(c.fids, n)
+	}
+}
+
+func (c *conn) handle(t *Fcall) (*Fcall, error) {
+	switch t.Type {
+	case Tauth:
+		return nil, ErrNoAuth
+	case Tattach:
+		return c.attach(t)
+	case Tflush:
+		// The old request goes on, but its reply is dropped.
+		c.mu.Lock()
+		if t.Oldtag != t.Tag {
+			delete(c.inflight, t.Oldtag)
+		}
+		c.mu.Unlock()
+		return &Fcall{Type: Rflush}, nil
+	case Twalk:
+		return c.walk(t)
+	case Topen:
+		return c.open(t)
+	case Tcreate:
+		return nil, ErrPerm
+	case Tread:
+		return c.read(t)
+	case Twrite:
+		return c.write(t)
+	case Tclunk:
+		return c.clunk(t, Rclunk)
+	case Tremove:
+		// remove clunks the fid even if it fails
+		c.clunk(t, Rremove)
+		return nil, ErrPerm
+	case Tstat:
+		return c.stat(t)
+	case Twstat:
+		// Truncating synthetic files is harmless; accept and ignore.
+		if _, _, ok := c.getFid(t.Fid); !ok {
+			return nil, ErrBadFid
+		}
+		return &Fcall{Type: Rwstat}, nil
+	}
+	return nil, ErrBadMessage
+}
+
+func (c *conn) version(t *Fcall) (*Fcall, error) {
+	if t.Msize < minMsize {
+		return nil, ErrMsize
+	}
+	c.clunkAll()
+	c.mu.Lock()
+	c.msize = min(t.Msize, c.msize)
+	msize := c.msize
+	c.mu.Unlock()
+	v := Version
+	if !strings.HasPrefix(t.Version, Version) {
+		v = "unknown"
+	}
+	return &Fcall{Type: Rversion, Msize: msize, Version: v}, nil
+}
+
+func (c *conn) attach(t *Fcall) (*Fcall, error) {
+	if t.Afid != NOFID {
+		return nil, ErrNoAuth
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.current(t) {
+		return nil, errFlushed
+	}
+	if _, ok := c.fids[t.Fid]; ok {
+		return nil, ErrFidInUse
+	}
+	c.session.Uname = t.Uname
+	f := &fid{path: []Node{c.srv.Root}}
+	c.fids[t.Fid] = f
+	return &Fcall{Type: Rattach, Qid: qid(f.path)}, nil
+}
+
+// qid makes a stable qid from the path names of a node.
+func qid(path []Node) Qid {
+	h := fnv.New64a()
+	for _, n := range path[1:] {
+		h.Write([]byte("/" + n.Name()))
+	}
+	q := Qid{Path: h.Sum64()}
+	if _, ok := path[len(path)-1].(Directory); ok {
+		q.Type = QTDIR
+	}
+	return q
+}
+
+func (c *conn) walk(t *Fcall) (*Fcall, error) {
+	_, f, ok := c.getFid(t.Fid)
+	if !ok {
+		return nil, ErrBadFid
+	}
+	if f.open {
+		return nil, ErrOpen
+	}
+
+	path := append([]Node(nil), f.path...)
+	r := &Fcall{Type: Rwalk}
+	for i, name := range t.Wname {
+		var next Node
+		var err error
+		d, ok := path[len(path)-1].(Directory)
+		switch {
+		case !ok:
+			err = ErrNotDir
+		case name == "..":
+			if len(path) > 1 {
+				path = path[:len(path)-1]
+			}
+		case name == ".":
+		default:
+			next, err = d.Walk(c.session, name)
+		}
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			return r, nil
+		}
+		if next != nil {
+			path = append(path, next)
+		}
+		r.Wqid = append(r.Wqid, qid(path))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.current(t) {
+		return nil, errFlushed
+	}
+	if _, ok := c.fids[t.Newfid]; ok && t.Newfid != t.Fid {
+		return nil, ErrFidInUse
+	}
+	c.fids[t.Newfid] = &fid{path: path}
+	return r, nil
+}
+
+func (c *conn) open(t *Fcall) (*Fcall, error) {
+	fp, f, ok := c.getFid(t.Fid)
+	if !ok {
+		return nil, ErrBadFid
+	}
+	if f.open {
+		return nil, ErrOpen
+	}
+
+	var h Handle
+	var dir []byte
+	switch n := f.node().(type) {
+	case Directory:
+		if t.Mode&3 != OREAD {
+			return nil, ErrIsDir
+		}
+		children, err := n.List(c.session)
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			dir = append(dir, c.dirOf(append(f.path[:len(f.path):len(f.path)], child)).Bytes()...)
+		}
+	case File:
+		if t.Mode&3 != OREAD && !n.Writable() {
+			return nil, ErrPerm
+		}
+		var err error
+		if h, err = n.Open(c.session, t.Mode); err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.current(t) {
+		if h != nil {
+			h.Close()
+		}
+		return nil, errFlushed
+	}
+	if fp.open || c.fids[t.Fid] != fp {
+		if h != nil {
+			h.Close()
+		}
+		return nil, ErrOpen
+	}
+	fp.open, fp.handle, fp.dir = true, h, dir
+	return &Fcall{Type: Ropen, Qid: qid(f.path), Iounit: c.msize - IOHDRSZ}, nil
+}
+
+func (c *conn) read(t *Fcall) (*Fcall, error) {
+	_, f, ok := c.getFid(t.Fid)
+	if !ok {
+		return nil, ErrBadFid
+	}
+	if !f.open {
+		return nil, ErrNotOpen
+	}
+	c.mu.Lock()
+	count := min(t.Count, c.msize-IOHDRSZ)
+	c.mu.Unlock()
+
+	if f.handle == nil {
+		// Directory reads return whole entries only.
+		off := int(t.Offset)
+		if off > len(f.dir) {
+			return &Fcall{Type: Rread}, nil
+		}
+		end := off
+		for end+2 <= len(f.dir) {
+			n := int(f.dir[end]) | int(f.dir[end+1])<<8 + 2
+			if end+n-off > int(count) {
+				break
+			}
+			end += n
+		}
+		return &Fcall{Type: Rread, Data: f.dir[off:end]}, nil
+	}
+
+	buf := make([]byte, count)
+	n, err := f.handle.ReadAt(buf, int64(t.Offset))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return &Fcall{Type: Rread, Data: buf[:n]}, nil
+}
+
+func (c *conn) write(t *Fcall) (*Fcall, error) {
+	_, f, ok := c.getFid(t.Fid)
+	if !ok {
+		return nil, ErrBadFid
+	}
+	if !f.open || f.handle == nil {
+		return nil, ErrNotOpen
+	}
+	n, err := f.handle.WriteAt(t.Data, int64(t.Offset))
+	if err != nil {
+		return nil, err
+	}
+	return &Fcall{Type: Rwrite, Count: uint32(n)}, nil
+}
+
+func (c *conn) clunk(t *Fcall, typ uint8) (*Fcall, error) {
+	c.mu.Lock()
+	f, ok := c.fids[t.Fid]
+	delete(c.fids, t.Fid)
+	c.mu.Unlock()
+	if !ok {
+		return nil, ErrBadFid
+	}
+	if f.handle != nil {
+		if err := f.handle.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return &Fcall{Type: typ}, nil
+}
+
+func (c *conn) stat(t *Fcall) (*Fcall, error) {
+	_, f, ok := c.getFid(t.Fid)
+	if !ok {
+		return nil, ErrBadFid
+	}
+	return &Fcall{Type: Rstat, Stat: c.dirOf(f.path).Bytes()}, nil
+}
+
+func (c *conn) dirOf(path []Node) *Dir {
+	n := path[len(path)-1]
+	d := &Dir{
+		Qid:   qid(path),
+		Mode:  0444,
+		Atime: c.srv.start,
+		Mtime: c.srv.start,
+		Name:  n.Name(),
+		Uid:   "tlg",
+		Gid:   "tlg",
+		Muid:  "tlg",
+	}
+	if len(path) == 1 {
+		d.Name = "/"
+	}
+	switch n := n.(type) {
+	case Directory:
+		d.Mode = DMDIR | 0555
+	case File:
+		if n.Writable() {
+			d.Mode = 0666
+		}
+	}
+	return d
+}
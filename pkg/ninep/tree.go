@@ -0,0 +1,103 @@
+package ninep
+
+import (
+	"io"
+	"sync"
+)
+
+// StaticDir is a directory with a fixed list of children.
+type StaticDir struct {
+	DirName  string
+	Children []Node
+}
+
+func (d *StaticDir) Name() string { return d.DirName }
+
+func (d *StaticDir) Walk(s *Session, name string) (Node, error) {
+	for _, c := range d.Children {
+		if c.Name() == name {
+			return c, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (d *StaticDir) List(s *Session) ([]Node, error) {
+	return d.Children, nil
+}
+
+// DirFunc is a directory whose children are computed on demand.
+// WalkFn may accept names that ListFn does not list.
+type DirFunc struct {
+	DirName string
+	WalkFn  func(s *Session, name string) (Node, error)
+	ListFn  func(s *Session) ([]Node, error)
+}
+
+func (d *DirFunc) Name() string { return d.DirName }
+
+func (d *DirFunc) Walk(s *Session, name string) (Node, error) {
+	return d.WalkFn(s, name)
+}
+
+func (d *DirFunc) List(s *Session) ([]Node, error) {
+	if d.ListFn == nil {
+		return nil, nil
+	}
+	return d.ListFn(s)
+}
+
+// FileFunc is a file whose contents are generated by ReadFn when it is
+// opened. If WriteFn is set the file is writable; WriteFn gets the
+// data of every write.
+type FileFunc struct {
+	FileName string
+	ReadFn   func(s *Session) ([]byte, error)
+	WriteFn  func(s *Session, data []byte) error
+}
+
+func (f *FileFunc) Name() string { return f.FileName }
+
+func (f *FileFunc) Writable() bool { return f.WriteFn != nil }
+
+func (f *FileFunc) Open(s *Session, mode uint8) (Handle, error) {
+	h := &bytesHandle{s: s, write: f.WriteFn}
+	if mode&3 != OWRITE && f.ReadFn != nil {
+		data, err := f.ReadFn(s)
+		if err != nil {
+			return nil, err
+		}
+		h.data = data
+	}
+	return h, nil
+}
+
+type bytesHandle struct {
+	mu    sync.Mutex
+	s     *Session
+	data  []byte
+	write func(s *Session, data []byte) error
+}
+
+func (h *bytesHandle) ReadAt(p []byte, off int64) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if off >= int64(len(h.data)) {
+		return 0, io.EOF
+	}
+	return copy(p, h.data[off:]), nil
+}
+
+func (h *bytesHandle) WriteAt(p []byte, off int64) (int, error) {
+	if h.write == nil {
+		return 0, ErrPerm
+	}
+	if err := h.write(h.s, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (h *bytesHandle) Close() error {
+	return nil
+}
@@ -0,0 +1,386 @@
+// Package server serves the corpus texts, the morphology data and the
+// dictionaries as a JSON API over HTTP. It only reads local files.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"tlgread/pkg/corpus"
+	"tlgread/pkg/lexicon"
+	"tlgread/pkg/morph"
+	"tlgread/pkg/tlgcore"
+)
+
+// Config names the files the server reads. Empty dictionary or
+// analyses paths disable the corresponding endpoints.
+type Config struct {
+	Corpus []string // corpus directories, e.g. TLG-E and PHI-5
+
+	Analyses, AnalysesIDT           string // greek-analyses.txt/.idt
+	LatinAnalyses, LatinAnalysesIDT string // latin-analyses.txt/.idt
+	LSJ, LSJIDT                     string // grc.lsj.xml, lsj.idt
+	LS, LSIDT                       string // lat.ls.perseus-eng1.xml, ls.idt
+}
+
+type Server struct {
+	cfg     Config
+	mux     *http.ServeMux
+	files   map[string]string // author ID (tlg0012) -> text file
+	ids     []string
+	authors map[string]*corpus.Authors // corpus directory -> names
+
+	greek, latin      *morph.Analyses
+	lsjIndex, lsIndex map[string]int64
+}
+
+// New indexes the corpus directories and opens the morphology files.
+func New(cfg Config) (*Server, error) {
+	s := &Server{
+		cfg:     cfg,
+		mux:     http.NewServeMux(),
+		files:   make(map[string]string),
+		authors: make(map[string]*corpus.Authors),
+	}
+
+	for _, dir := range cfg.Corpus {
+		files, err := corpus.Files(dir)
+		if err != nil {
+			return nil, err
+		}
+		s.authors[dir] = corpus.NewAuthors(dir)
+		for _, f := range files {
+			id := strings.TrimSuffix(filepath.Base(f), filepath.Ext(f))
+			if _, dup := s.files[id]; !dup {
+				s.files[id] = f
+				s.ids = append(s.ids, id)
+			}
+		}
+	}
+	sort.Strings(s.ids)
+
+	s.greek = openAnalyses(cfg.Analyses, cfg.AnalysesIDT)
+	s.latin = openAnalyses(cfg.LatinAnalyses, cfg.LatinAnalysesIDT)
+	if exists(cfg.LSJ) {
+		s.lsjIndex = lexicon.LoadLSJIndex(cfg.LSJIDT)
+	}
+	if exists(cfg.LS) {
+		s.lsIndex = lexicon.LoadLSJIndex(cfg.LSIDT)
+	}
+
+	s.routes()
+	return s, nil
+}
+
+func exists(path string) bool {
+	if path == "" {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func openAnalyses(path, idtPath string) *morph.Analyses {
+	if !exists(path) {
+		return nil
+	}
+	a, err := morph.OpenAnalyses(path, idtPath)
+	if err != nil {
+		log.Printf("server: %s: %v", idtPath, err)
+		return nil
+	}
+	return a
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("GET /api/authors", s.handleAuthors)
+	s.mux.HandleFunc("GET /api/authors/{author}", s.handleWorks)
+	s.mux.HandleFunc("GET /api/authors/{author}/works/{work}", s.handleText)
+	s.mux.HandleFunc("GET /api/urn/{urn}", s.handleURN)
+	s.mux.HandleFunc("GET /api/morph/{word...}", s.handleMorph)
+	s.mux.HandleFunc("GET /api/lsj/{lemma...}", s.handleDict(false))
+	s.mux.HandleFunc("GET /api/ls/{lemma...}", s.handleDict(true))
+	s.uiRoutes()
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, format string, args ...any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+type Author struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func (s *Server) authorName(id string) string {
+	path := s.files[id]
+	return s.authors[filepath.Dir(path)].Name(path)
+}
+
+func (s *Server) handleAuthors(w http.ResponseWriter, r *http.Request) {
+	authors := make([]Author, 0, len(s.ids))
+	for _, id := range s.ids {
+		authors = append(authors, Author{ID: id, Name: s.authorName(id)})
+	}
+	writeJSON(w, authors)
+}
+
+type Work struct {
+	ID        string   `json:"id"`
+	Title     string   `json:"title"`
+	Citations []string `json:"citations"` // level labels, outermost first
+	URN       string   `json:"urn"`
+}
+
+type WorkList struct {
+	Author Author `json:"author"`
+	Works  []Work `json:"works"`
+}
+
+// open returns the parser of an author named in the request path.
+func (s *Server) open(w http.ResponseWriter, id string) *tlgcore.Parser {
+	path, ok := s.files[strings.ToLower(id)]
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown author %q", id)
+		return nil
+	}
+	p, err := tlgcore.OpenText(path)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return nil
+	}
+	return p
+}
+
+func citationLabels(meta *tlgcore.WorkMetadata) []string {
+	labels := []string{}
+	for _, c := range meta.Citations {
+		labels = append(labels, c.Label)
+	}
+	return labels
+}
+
+func (s *Server) handleWorks(w http.ResponseWriter, r *http.Request) {
+	id := strings.ToLower(r.PathValue("author"))
+	p := s.open(w, id)
+	if p == nil {
+		return
+	}
+	defer p.Close()
+
+	list := WorkList{Author: Author{ID: id, Name: s.authorName(id)}, Works: []Work{}}
+	for _, meta := range p.IDTData {
+		list.Works = append(list.Works, Work{
+			ID:        meta.ID,
+			Title:     meta.Title,
+			Citations: citationLabels(meta),
+			URN:       tlgcore.NewURN(filepath.Base(p.File.Name()), meta.ID).String(),
+		})
+	}
+	sort.Slice(list.Works, func(i, j int) bool {
+		a, _ := strconv.Atoi(list.Works[i].ID)
+		b, _ := strconv.Atoi(list.Works[j].ID)
+		return a < b
+	})
+	writeJSON(w, list)
+}
+
+type Line struct {
+	Citation string `json:"citation"`
+	Text     string `json:"text"`
+	HTML     string `json:"html"` // Text with fonts, titles, speakers as HTML
+	Beta     string `json:"beta"`
+	URN      string `json:"urn"`
+}
+
+type Passage struct {
+	Author Author `json:"author"`
+	Work   Work   `json:"work"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+	Lines  []Line `json:"lines"`
+}
+
+func (s *Server) handleText(w http.ResponseWriter, r *http.Request) {
+	id := strings.ToLower(r.PathValue("author"))
+	q := r.URL.Query()
+	s.passage(w, id, r.PathValue("work"), q.Get("from"), q.Get("to"))
+}
+
+func (s *Server) handleURN(w http.ResponseWriter, r *http.Request) {
+	u, err := tlgcore.ParseURN(r.PathValue("urn"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	id := strings.TrimSuffix(u.FileName(), ".txt")
+	to := u.To
+	if to == "" {
+		to = u.From
+	}
+	s.passage(w, id, u.WorkID(), u.From, to)
+}
+
+func (s *Server) passage(w http.ResponseWriter, id, work, from, to string) {
+	p := s.open(w, id)
+	if p == nil {
+		return
+	}
+	defer p.Close()
+
+	workID := tlgcore.NormalizeID(work)
+	meta, ok := p.IDTData[workID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "%s has no work %s", id, work)
+		return
+	}
+
+	var lines []*tlgcore.Line
+	var err error
+	if from != "" || to != "" {
+		lines, err = p.ExtractRange(workID, from, to)
+	} else {
+		lines, err = p.WorkLines(workID)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+
+	res := Passage{
+		Author: Author{ID: id, Name: s.authorName(id)},
+		Work: Work{
+			ID:        workID,
+			Title:     meta.Title,
+			Citations: citationLabels(meta),
+			URN:       tlgcore.NewURN(filepath.Base(p.File.Name()), workID).String(),
+		},
+		From:  from,
+		To:    to,
+		Lines: make([]Line, 0, len(lines)),
+	}
+	spans := tlgcore.NewSpanReader(p.IsLatinFile)
+	for _, l := range lines {
+		res.Lines = append(res.Lines, Line{
+			Citation: l.Citation,
+			Text:     l.Text,
+			HTML:     tlgcore.HTML(spans.Spans(l.Beta)),
+			Beta:     l.Beta,
+			URN:      p.URN(l).String(),
+		})
+	}
+	writeJSON(w, res)
+}
+
+type Analysis struct {
+	Form       string `json:"form"`
+	Lemma      string `json:"lemma"`    // Beta Code or Latin
+	Headword   string `json:"headword"` // Unicode
+	ShortDef   string `json:"shortdef"`
+	Morphology string `json:"morphology"`
+}
+
+// analyses looks a word up in the Greek or Latin analyses. It returns
+// nil if no analyses file is configured for the language.
+func (s *Server) analyses(latin bool, word string) []Analysis {
+	a := s.greek
+	if latin {
+		a = s.latin
+	}
+	if a == nil {
+		return nil
+	}
+
+	res := []Analysis{}
+	results, err := a.LookupWord(morph.BetaQuery(word))
+	if err != nil {
+		return res
+	}
+	for _, m := range results {
+		fields := strings.Fields(m.Lemma)
+		if len(fields) == 0 {
+			continue
+		}
+		an := Analysis{Form: m.Form, Lemma: fields[0], Headword: fields[0], ShortDef: m.ShortDef, Morphology: m.Morphology}
+		if !latin {
+			an.Form = tlgcore.ToGreek(m.Form)
+			an.Headword = tlgcore.ToGreek(fields[0])
+		}
+		res = append(res, an)
+	}
+	return res
+}
+
+func (s *Server) handleMorph(w http.ResponseWriter, r *http.Request) {
+	res := s.analyses(r.URL.Query().Get("lang") == "lat", r.PathValue("word"))
+	if res == nil {
+		writeError(w, http.StatusServiceUnavailable, "no analyses file configured")
+		return
+	}
+	writeJSON(w, res)
+}
+
+type Entry struct {
+	Headword string `json:"headword"`
+	Key      string `json:"key"`
+	Text     string `json:"text"`
+}
+
+func (s *Server) entries(latin bool, lemmata []string) ([]Entry, error) {
+	path, index := s.cfg.LSJ, s.lsjIndex
+	if latin {
+		path, index = s.cfg.LS, s.lsIndex
+	}
+
+	seen := make(map[int64]bool)
+	res := []Entry{}
+	for _, lemma := range lemmata {
+		entries, err := lexicon.Lookup(path, lemma, index, seen, !latin)
+		for _, e := range entries {
+			res = append(res, Entry{Headword: e.Headword, Key: e.Key, Text: e.Text()})
+		}
+		if err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+func (s *Server) handleDict(latin bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if (latin && s.lsIndex == nil) || (!latin && s.lsjIndex == nil) {
+			writeError(w, http.StatusServiceUnavailable, "no dictionary configured")
+			return
+		}
+		lemma := r.PathValue("lemma")
+		if !latin {
+			lemma = morph.BetaQuery(lemma)
+		}
+		res, err := s.entries(latin, []string{lemma})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "%v", err)
+			return
+		}
+		writeJSON(w, res)
+	}
+}
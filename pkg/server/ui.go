@@ -0,0 +1,62 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"tlgread/pkg/morph"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// Lookup is the answer to a click on a word in the reader: its
+// analyses and the dictionary entries of their lemmata.
+type Lookup struct {
+	Word     string     `json:"word"`
+	Analyses []Analysis `json:"analyses"`
+	Entries  []Entry    `json:"entries"`
+}
+
+func (s *Server) uiRoutes() {
+	web, _ := fs.Sub(webFiles, "web")
+	s.mux.Handle("GET /", http.FileServerFS(web))
+	s.mux.HandleFunc("GET /api/lookup/{word...}", s.handleLookup)
+}
+
+func (s *Server) handleLookup(w http.ResponseWriter, r *http.Request) {
+	word := strings.TrimRight(r.PathValue("word"), "'’")
+	latin := r.URL.Query().Get("lang") == "lat"
+
+	res := Lookup{Word: word, Analyses: s.analyses(latin, word), Entries: []Entry{}}
+	if res.Analyses == nil {
+		res.Analyses = []Analysis{}
+	}
+	if (latin && s.lsIndex == nil) || (!latin && s.lsjIndex == nil) {
+		writeJSON(w, res)
+		return
+	}
+
+	var lemmata []string
+	for _, a := range res.Analyses {
+		lemmata = append(lemmata, a.Lemma)
+	}
+	if len(lemmata) == 0 {
+		// Not analysed: try the word itself as a headword.
+		lemma := word
+		if !latin {
+			lemma = morph.BetaQuery(word)
+		}
+		lemmata = append(lemmata, lemma)
+	}
+
+	entries, err := s.entries(latin, lemmata)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	res.Entries = entries
+	writeJSON(w, res)
+}
@@ -0,0 +1,193 @@
+// Reader for the JSON API of cmd/serve. The location hash holds the
+// state: #/tlg0012 lists the works, #/tlg0012/1?from=1.1&to=1.50 shows
+// a passage.
+
+const $ = (id) => document.getElementById(id);
+let authors = [];
+let current = {author: "", work: ""};
+
+function el(tag, cls, text) {
+	const e = document.createElement(tag);
+	if (cls) e.className = cls;
+	if (text !== undefined) e.textContent = text;
+	return e;
+}
+
+async function api(path) {
+	const res = await fetch("api/" + path);
+	const data = await res.json();
+	if (!res.ok) throw new Error(data.error || res.statusText);
+	return data;
+}
+
+function isLatin(id) {
+	return /^(lat|civ|cop|phi)/i.test(id);
+}
+
+function showError(node, err) {
+	node.replaceChildren(el("p", "error", err.message));
+}
+
+function showAuthors() {
+	const q = $("filter").value.toLowerCase();
+	const list = $("list");
+	list.replaceChildren();
+	for (const a of authors) {
+		if (q && !a.name.toLowerCase().includes(q) && !a.id.includes(q)) continue;
+		const li = el("li");
+		li.append(el("span", "id", a.id), a.name);
+		li.onclick = () => { location.hash = "#/" + a.id; };
+		list.append(li);
+	}
+}
+
+function crumbs(author, work) {
+	const c = $("crumbs");
+	c.replaceChildren();
+	if (!author) return;
+	const a = el("a", "", author.name);
+	a.href = "#/" + author.id;
+	c.append(a);
+	if (work) c.append(" — " + work.title);
+}
+
+async function showWorks(id) {
+	const list = $("list");
+	try {
+		const data = await api("authors/" + id);
+		crumbs(data.author);
+		$("filter").value = "";
+		list.replaceChildren();
+		for (const w of data.works) {
+			const li = el("li");
+			li.append(el("span", "id", w.id), w.title);
+			li.onclick = () => { location.hash = "#/" + id + "/" + w.id; };
+			list.append(li);
+		}
+	} catch (err) {
+		showError(list, err);
+	}
+}
+
+// words wraps every word of a line in a clickable span.
+function words(text) {
+	const frag = document.createDocumentFragment();
+	for (const part of text.split(/([\p{L}\p{M}’']+)/u)) {
+		if (/^[\p{L}\p{M}]/u.test(part)) {
+			const w = el("span", "w", part);
+			w.onclick = () => lookup(w);
+			frag.append(w);
+		} else if (part) {
+			frag.append(part);
+		}
+	}
+	return frag;
+}
+
+// styled parses the HTML of a line, with its italics, titles and
+// speakers, and wraps the words of its text like words does.
+function styled(html) {
+	const t = document.createElement("template");
+	t.innerHTML = html;
+	const walker = document.createTreeWalker(t.content, NodeFilter.SHOW_TEXT);
+	const nodes = [];
+	while (walker.nextNode()) nodes.push(walker.currentNode);
+	for (const n of nodes) n.replaceWith(words(n.textContent));
+	return t.content;
+}
+
+async function showText(id, work, from, to) {
+	const text = $("text");
+	text.replaceChildren(el("p", "hint", "Loading…"));
+	let q = "";
+	if (from || to) q = "?" + new URLSearchParams({from: from, to: to});
+	try {
+		const data = await api("authors/" + id + "/works/" + work + q);
+		crumbs(data.author, data.work);
+		const frag = document.createDocumentFragment();
+		for (const l of data.lines) {
+			const div = el("div", "line");
+			div.title = l.urn;
+			div.append(el("span", "cit", l.citation));
+			const t = el("span", "txt");
+			t.append(l.html ? styled(l.html) : words(l.text));
+			div.append(t);
+			frag.append(div);
+		}
+		if (!data.lines.length) frag.append(el("p", "hint", "No lines in this range."));
+		text.replaceChildren(frag);
+		text.scrollTop = 0;
+	} catch (err) {
+		showError(text, err);
+	}
+}
+
+async function lookup(span) {
+	document.querySelectorAll(".w.sel").forEach((w) => w.classList.remove("sel"));
+	span.classList.add("sel");
+
+	const dict = $("dict");
+	const word = span.textContent;
+	let q = isLatin(current.author) ? "?lang=lat" : "";
+	try {
+		const data = await api("lookup/" + encodeURIComponent(word) + q);
+		const frag = document.createDocumentFragment();
+		frag.append(el("h2", "", word));
+		if (!data.analyses.length) frag.append(el("p", "hint", "No analysis found."));
+		for (const a of data.analyses) {
+			const p = el("p", "analysis");
+			p.append(el("span", "head", a.headword), " ",
+				el("span", "morph", a.morphology), " — " + a.shortdef);
+			frag.append(p);
+		}
+		for (const e of data.entries) {
+			const d = el("div", "entry");
+			d.append(el("h3", "", e.headword), e.text);
+			frag.append(d);
+		}
+		dict.replaceChildren(frag);
+		dict.scrollTop = 0;
+	} catch (err) {
+		showError(dict, err);
+	}
+}
+
+async function route() {
+	const [path, query] = location.hash.replace(/^#\/?/, "").split("?");
+	const [author, work] = path.split("/");
+	const params = new URLSearchParams(query || "");
+	$("from").value = params.get("from") || "";
+	$("to").value = params.get("to") || "";
+
+	if (!author) {
+		current = {author: "", work: ""};
+		crumbs();
+		showAuthors();
+		return;
+	}
+	if (author !== current.author) await showWorks(author);
+	current = {author: author, work: work || ""};
+	if (work) showText(author, work, params.get("from"), params.get("to"));
+}
+
+$("filter").oninput = () => {
+	if (current.author) location.hash = "#";
+	showAuthors();
+};
+
+$("range").onsubmit = (ev) => {
+	ev.preventDefault();
+	if (!current.work) return;
+	const params = new URLSearchParams();
+	if ($("from").value) params.set("from", $("from").value);
+	if ($("to").value) params.set("to", $("to").value);
+	const q = params.toString();
+	location.hash = "#/" + current.author + "/" + current.work + (q ? "?" + q : "");
+};
+
+window.onhashchange = route;
+
+api("authors").then((data) => {
+	authors = data;
+	route();
+}).catch((err) => showError($("list"), err));
@@ -0,0 +1,199 @@
+package tlgcore
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// AuthorMatch is a result of AuthorTable.Search.
+type AuthorMatch struct {
+	Record *AuthorRecord
+	Score  float64 // 1 for an exact match, less for partial ones
+}
+
+// Search finds authors by a partial name in Latin or Greek letters,
+// e.g. "thucyd" or "Θουκυδ", or by number. Case, diacritics and the
+// usual differences between Latin and Greek spellings are ignored.
+// Matches are ordered by score, best first.
+func (t *AuthorTable) Search(query string) []AuthorMatch {
+	if r := t.Lookup(strings.TrimSpace(query)); r != nil {
+		return []AuthorMatch{{r, 1}}
+	}
+	q := nameKey(query)
+	if len(q) == 0 {
+		return nil
+	}
+
+	var matches []AuthorMatch
+	for _, r := range t.Records {
+		best := 0.0
+		for _, name := range append([]string{r.Name}, r.AltNames...) {
+			best = max(best, scoreName(q, nameKey(name)))
+		}
+		if best > 0 {
+			matches = append(matches, AuthorMatch{r, best})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
+
+// scoreName is the mean score of the query words, each against its
+// best match among the words of the name; 0 if one of them is missing.
+func scoreName(query, name []string) float64 {
+	total := 0.0
+	for _, q := range query {
+		best := 0.0
+		for _, w := range name {
+			best = max(best, scoreWord(q, w))
+		}
+		if best == 0 {
+			return 0
+		}
+		total += best
+	}
+	return total / float64(len(query))
+}
+
+func scoreWord(q, w string) float64 {
+	lq, lw := float64(len(q)), float64(len(w))
+	switch {
+	case q == w:
+		return 1
+	case strings.HasPrefix(w, q):
+		return 0.8 + 0.1*lq/lw
+	case len(q) >= 3 && strings.Contains(w, q):
+		return 0.6 + 0.1*lq/lw
+	case len(q) < 4:
+		return 0
+	}
+	// Misspellings: compare with the word and with its prefix of the
+	// query's length.
+	d := min(levenshtein(q, w), levenshtein(q, w[:min(len(w), len(q))]))
+	sim := 1 - float64(d)/lq
+	if sim < 0.75 {
+		return 0
+	}
+	return 0.5 * sim
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// latinKey levels the spellings of Greek names in Latin: Thucydides,
+// Thoukydides and Thukydides all become tukidides.
+var latinKey = strings.NewReplacer(
+	"ph", "f", "th", "t", "ch", "k", "rh", "r",
+	"ae", "e", "ai", "e", "oe", "e", "oi", "e", "ei", "i", "ou", "u",
+	"c", "k", "q", "k", "y", "i", "j", "i", "v", "u", "x", "ks", "h", "",
+)
+
+// betaLatin transliterates the Beta Code letters; ou is handled apart.
+var betaLatin = map[rune]string{
+	'a': "a", 'b': "b", 'g': "g", 'd': "d", 'e': "e", 'z': "z", 'h': "e",
+	'q': "th", 'i': "i", 'k': "k", 'l': "l", 'm': "m", 'n': "n", 'c': "x",
+	'o': "o", 'p': "p", 'r': "r", 's': "s", 't': "t", 'u': "y", 'f': "ph",
+	'x': "ch", 'y': "ps", 'w': "o", 'v': "w", '(': "h",
+}
+
+// transliterate turns Greek into Latin letters by way of Beta Code.
+func transliterate(s string) string {
+	beta := []rune(strings.ToLower(ToBetaCode(s)))
+	var b strings.Builder
+	for i := 0; i < len(beta); i++ {
+		r := beta[i]
+		switch {
+		case r == 'o' && i+1 < len(beta) && beta[i+1] == 'u':
+			b.WriteString("ou")
+			i++
+		case r == 'g' && i+1 < len(beta) && strings.ContainsRune("gkxc", beta[i+1]):
+			b.WriteString("n")
+		case betaLatin[r] != "":
+			b.WriteString(betaLatin[r])
+		case r == ' ':
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func isGreek(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Greek, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// nameKey returns the words of a name reduced for comparison.
+func nameKey(s string) []string {
+	if isGreek(s) {
+		s = transliterate(s)
+	}
+	s = strings.Map(func(r rune) rune {
+		r = unicode.ToLower(removeDiacritic(r))
+		if r >= 'a' && r <= 'z' {
+			return r
+		}
+		return ' '
+	}, s)
+
+	var words []string
+	for _, w := range strings.Fields(s) {
+		w = latinKey.Replace(w)
+		// Collapse doubled letters and level Greek and Latin endings.
+		var b strings.Builder
+		for i := 0; i < len(w); i++ {
+			if i == 0 || w[i] != w[i-1] {
+				b.WriteByte(w[i])
+			}
+		}
+		w = b.String()
+		if len(w) > 4 && strings.HasSuffix(w, "os") {
+			w = w[:len(w)-2] + "us"
+		}
+		// Greek names in -ων are Latin -o or -on: Plato, Zeno, Solon.
+		if len(w) > 3 && strings.HasSuffix(w, "on") {
+			w = w[:len(w)-1]
+		}
+		if w != "" {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// removeDiacritic strips the accent from a precomposed Latin letter,
+// so that a query such as "Thucydidès" finds Thucydides.
+func removeDiacritic(r rune) rune {
+	if r < 0xC0 || r > 0x17F {
+		return r
+	}
+	const from = "ÀÁÂÃÄÅàáâãäåÈÉÊËèéêëÌÍÎÏìíîïÒÓÔÕÖòóôõöÙÚÛÜùúûüÝýÿÇçÑñ"
+	const to = "AAAAAAaaaaaaEEEEeeeeIIIIiiiiOOOOOoooooUUUUuuuuYyyCcNn"
+	if i := strings.IndexRune(from, r); i >= 0 {
+		return rune(to[len([]rune(from[:i]))])
+	}
+	return r
+}
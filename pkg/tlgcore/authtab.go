@@ -0,0 +1,354 @@
+package tlgcore
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuthorRecord is an entry of authtab.dir. An entry is the author
+// number, the name (the part between &1 and & is the name proper, what
+// follows the closing & the epithet) and optional fields introduced by
+// a byte with the high bit set:
+//
+//	0x80	alternate name ("synonym")
+//	0x81	remarks
+//	0x82	file size
+//	0x83	language code
+//
+// The entry ends with 0xFF.
+type AuthorRecord struct {
+	ID       string   // e.g. "TLG0012"
+	Name     string   // e.g. "Homerus"
+	Epithet  string   // e.g. "Epic."
+	AltNames []string // 0x80 fields
+	Remarks  string   // 0x81 field
+	Size     string   // 0x82 field
+	Language string   // 0x83 field
+	Section  string   // section the entry is in, e.g. "TLG", "CIV", "COP"
+}
+
+// File returns the name of the text file of the author, e.g. tlg0012.txt.
+func (r *AuthorRecord) File() string {
+	return strings.ToLower(r.ID) + ".txt"
+}
+
+// IDT returns the name of the index file of the author, e.g. tlg0012.idt.
+func (r *AuthorRecord) IDT() string {
+	return strings.ToLower(r.ID) + ".idt"
+}
+
+// AuthorSection is a section marker of authtab.dir such as *CIV. The
+// file closes with *END.
+type AuthorSection struct {
+	Name  string // e.g. "CIV"
+	Title string // the rest of the marker entry, if any
+}
+
+// AuthorTable is the parsed authtab.dir of a corpus.
+type AuthorTable struct {
+	Records  []*AuthorRecord
+	Sections []AuthorSection
+	byID     map[string]*AuthorRecord
+}
+
+// ReadAuthorTable reads and parses an authtab.dir file.
+func ReadAuthorTable(path string) (*AuthorTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAuthorTable(data), nil
+}
+
+// ParseAuthorTable parses the contents of an authtab.dir file.
+func ParseAuthorTable(data []byte) *AuthorTable {
+	t := &AuthorTable{byID: make(map[string]*AuthorRecord)}
+	section := ""
+	i := 0
+	for i < len(data) {
+		if !isAuthorEntryStart(data[i:]) {
+			i++
+			continue
+		}
+		if data[i] == '*' {
+			var sec AuthorSection
+			sec, i = decodeAuthorSection(data, i)
+			if sec.Name == "END" {
+				break
+			}
+			t.Sections = append(t.Sections, sec)
+			section = sec.Name
+			continue
+		}
+
+		var rec *AuthorRecord
+		rec, i = decodeAuthorEntry(data, i)
+		rec.Section = section
+		if rec.Section == "" {
+			rec.Section = rec.ID[:3]
+		}
+		t.Records = append(t.Records, rec)
+		if _, dup := t.byID[rec.ID]; !dup {
+			t.byID[rec.ID] = rec
+		}
+	}
+	return t
+}
+
+// isAuthorEntryStart reports whether an entry starts at buf: a section
+// marker such as *CIV, or TLG, LAT, CIV or COP followed by a digit.
+func isAuthorEntryStart(buf []byte) bool {
+	if len(buf) < 4 {
+		return false
+	}
+	if buf[0] == '*' {
+		return buf[1] >= 'A' && buf[1] <= 'Z'
+	}
+
+	switch string(buf[:3]) {
+	case "TLG", "LAT", "CIV", "COP":
+		return buf[3] >= '0' && buf[3] <= '9'
+	}
+	return false
+}
+
+func decodeAuthorSection(data []byte, start int) (AuthorSection, int) {
+	i := start + 1
+	for i < len(data) && data[i] >= 'A' && data[i] <= 'Z' {
+		i++
+	}
+	sec := AuthorSection{Name: string(data[start+1 : i])}
+
+	var title strings.Builder
+	for i < len(data) && data[i] != 0xff && data[i] != 0xfe && !isAuthorEntryStart(data[i:]) {
+		if data[i] >= 32 && data[i] < 127 {
+			title.WriteByte(data[i])
+		}
+		i++
+	}
+	if i < len(data) && (data[i] == 0xff || data[i] == 0xfe) {
+		i++
+	}
+	sec.Title = cleanAuthorText(title.String())
+	return sec, i
+}
+
+// decodeAuthorEntry decodes the entry starting at data[start] and
+// returns the offset after it.
+func decodeAuthorEntry(data []byte, start int) (*AuthorRecord, int) {
+	rec := &AuthorRecord{}
+	i := start
+
+	// 1. Extract 7-character ID
+	end := min(start+7, len(data))
+	rec.ID = strings.TrimSpace(string(data[start:end]))
+	i = end
+
+	var preName, mainName, epithet bytes.Buffer
+	state := 0 // 0: pre-name/main-name, 1: inside name (after &1), 2: epithet
+
+	var field byte // code of the current optional field, 0 in the name
+	var value strings.Builder
+	endField := func() {
+		v := cleanAuthorText(value.String())
+		value.Reset()
+		switch field {
+		case 0x80:
+			if v != "" {
+				rec.AltNames = append(rec.AltNames, v)
+			}
+		case 0x81:
+			rec.Remarks = v
+		case 0x82:
+			rec.Size = v
+		case 0x83:
+			rec.Language = v
+		}
+	}
+
+	for i < len(data) {
+		b := data[i]
+
+		if b == 0xff || b == 0xfe {
+			i++
+			break
+		}
+		// Entries without a terminator end where the next one starts.
+		if field == 0 && i+4 < len(data) && isAuthorEntryStart(data[i:]) {
+			break
+		}
+
+		if b >= 0x80 {
+			endField()
+			field = b
+			i++
+			continue
+		}
+		if field != 0 {
+			value.WriteByte(b)
+			i++
+			continue
+		}
+
+		// Handle [2 and ]2 mapping
+		if b == '[' && i+1 < len(data) && data[i+1] == '2' {
+			writeAuthorName(state, '(', &preName, &mainName, &epithet)
+			i += 2
+			continue
+		}
+		if b == ']' && i+1 < len(data) && data[i+1] == '2' {
+			writeAuthorName(state, ')', &preName, &mainName, &epithet)
+			i += 2
+			continue
+		}
+
+		// Handle &1 marker
+		if b == '&' && i+1 < len(data) && data[i+1] == '1' {
+			state = 1
+			i += 2
+			continue
+		}
+
+		// A closing & (or one without &1) starts the epithet.
+		if b == '&' {
+			state = 2
+			i++
+			continue
+		}
+
+		if b >= 32 && b < 127 {
+			writeAuthorName(state, b, &preName, &mainName, &epithet)
+		}
+		i++
+	}
+	endField()
+
+	// Assembly: If no &1 was found, the text is in preName
+	mainStr := strings.TrimSpace(mainName.String())
+	preStr := strings.TrimSpace(preName.String())
+
+	if mainStr == "" && preStr != "" {
+		rec.Name = preStr
+	} else {
+		rec.Name = mainStr
+		if preStr != "" {
+			rec.Name = strings.Trim(preStr+" "+rec.Name, ", ")
+		}
+	}
+
+	rec.Epithet = strings.TrimSpace(epithet.String())
+	return rec, i
+}
+
+func writeAuthorName(state int, char byte, pre, main, epi *bytes.Buffer) {
+	switch state {
+	case 0:
+		pre.WriteByte(char)
+	case 1:
+		main.WriteByte(char)
+	case 2:
+		epi.WriteByte(char)
+	}
+}
+
+var authorTextReplacer = strings.NewReplacer("&1", "", "&", "", "[2", "(", "]2", ")")
+
+// cleanAuthorText removes the font codes from an optional field.
+func cleanAuthorText(s string) string {
+	var b strings.Builder
+	for _, r := range authorTextReplacer.Replace(s) {
+		if r >= 32 && r < 127 {
+			b.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// normalizeAuthorID turns tlg0012, TLG12, path/to/tlg0012.txt or
+// phi0474 into the form used in authtab.dir (TLG0012, LAT0474).
+func normalizeAuthorID(id string) string {
+	id = strings.ToUpper(filepath.Base(id))
+	id = strings.TrimSuffix(strings.TrimSuffix(id, ".TXT"), ".IDT")
+	if len(id) < 3 {
+		return id
+	}
+	prefix, num := id[:3], id[3:]
+	if prefix == "PHI" {
+		prefix = "LAT"
+	}
+	return fmt.Sprintf("%s%04s", prefix, num)
+}
+
+// Lookup returns the record of an author number such as tlg0012,
+// TLG0012 or a file name, or nil. A bare number is taken to be in the
+// section of the first record.
+func (t *AuthorTable) Lookup(id string) *AuthorRecord {
+	if id != "" && id[0] >= '0' && id[0] <= '9' && len(t.Records) > 0 {
+		id = t.Records[0].ID[:3] + id
+	}
+	return t.byID[normalizeAuthorID(id)]
+}
+
+// FindName returns the records whose name or one of whose alternate
+// names equals name, ignoring case.
+func (t *AuthorTable) FindName(name string) []*AuthorRecord {
+	var found []*AuthorRecord
+	for _, r := range t.Records {
+		if strings.EqualFold(r.Name, name) {
+			found = append(found, r)
+			continue
+		}
+		for _, alt := range r.AltNames {
+			if strings.EqualFold(alt, name) {
+				found = append(found, r)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// Name returns the name of an author, the ID itself if it is not in the
+// table.
+func (t *AuthorTable) Name(id string) string {
+	if r := t.Lookup(id); r != nil {
+		return r.Name
+	}
+	return id
+}
+
+type cachedAuthorTable struct {
+	modTime time.Time
+	table   *AuthorTable
+}
+
+var (
+	authorTablesMu sync.Mutex
+	authorTables   = make(map[string]cachedAuthorTable)
+)
+
+// LoadAuthorTable is ReadAuthorTable with a cache: the file is parsed
+// again only when it changes.
+func LoadAuthorTable(path string) (*AuthorTable, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	authorTablesMu.Lock()
+	defer authorTablesMu.Unlock()
+	if c, ok := authorTables[path]; ok && c.modTime.Equal(fi.ModTime()) {
+		return c.table, nil
+	}
+	t, err := ReadAuthorTable(path)
+	if err != nil {
+		return nil, err
+	}
+	authorTables[path] = cachedAuthorTable{fi.ModTime(), t}
+	return t, nil
+}
@@ -156,37 +156,20 @@ func handleQuotation(runes []rune, start int, out *bytes.Buffer, isLat bool, inQ
 	inQuot = inQuo
 	isLatin = isLat
 
-	switch command {
-	case "\"1":
-		out.WriteString("\"")
-	case "\"2":
-		out.WriteString("\"")
-	case "\"3":
-		out.WriteString("'")
-	case "\"4":
-		out.WriteString("'")
-	case "\"5":
-		out.WriteString("'")
-	case "\"6":
-		if !inQuo {
-			out.WriteString("«")
-			inQuot = true
-		} else {
-			out.WriteString("»")
-			inQuot = false
-		}
-	case "\"7":
-		if !inQuo {
-			out.WriteString("‹")
-			inQuot = true
-		} else {
-			out.WriteString("›")
-			inQuot = false
-		}
-	case "\"8":
-		out.WriteString("\"")
+	marks, ok := quotationCodes[command[1:]]
+	switch {
+	case !ok:
 		inQuot = false
+	case marks[0] == "":
+		out.WriteString(marks[1])
+		inQuot = false
+	case marks[1] == "":
+		out.WriteString(marks[0])
+	case !inQuo:
+		out.WriteString(marks[0])
+		inQuot = true
 	default:
+		out.WriteString(marks[1])
 		inQuot = false
 	}
 
@@ -197,152 +180,46 @@ func handleQuotation(runes []rune, start int, out *bytes.Buffer, isLat bool, inQ
 func handleOpenBracket(runes []rune, start int, out *bytes.Buffer, isLat bool, inQuo bool) (newIdx int, isLatin bool, inQuot bool) {
 	command, nextIdx := parseCommand(runes, start)
 
-	inQuot = inQuo
-	isLatin = isLat
-
-	switch command {
-	case "[":
-		out.WriteString("[")
-	case "[1":
-		out.WriteString("(")
-	case "[2":
-		out.WriteString("<")
-	case "[3":
-		out.WriteString("{")
-	case "[4":
-		out.WriteString("⟦")
-	case "[5":
-		out.WriteString("⌊")
-	case "[6":
-		out.WriteString("⌈")
-	case "[7":
-		out.WriteString("⌈")
-	case "[8":
-		out.WriteString("⌊")
-	case "[9":
-		out.WriteString("˙")
-	default:
+	if b, ok := openBracketCodes[command[1:]]; ok {
+		out.WriteString(b)
+	} else {
 		out.WriteString("[")
 	}
 
-	return nextIdx, isLatin, inQuot
+	return nextIdx, isLat, inQuo
 }
 
 // ]
 func handleCloseBracket(runes []rune, start int, out *bytes.Buffer, isLat bool, inQuo bool) (newIdx int, isLatin bool, inQuot bool) {
 	command, nextIdx := parseCommand(runes, start)
 
-	inQuot = inQuo
-	isLatin = isLat
-
-	switch command {
-	case "]":
-		out.WriteString("]")
-	case "]1":
-		out.WriteString(")")
-	case "]2":
-		out.WriteString(">")
-	case "]3":
-		out.WriteString("}")
-	case "]4":
-		out.WriteString("⟧")
-	case "]5":
-		out.WriteString("⌋")
-	case "]6":
-		out.WriteString("⌉")
-	case "]7":
-		out.WriteString("⌋")
-	case "]8":
-		out.WriteString("⌉")
-	case "]9":
-		out.WriteString("˙")
-	default:
+	if b, ok := closeBracketCodes[command[1:]]; ok {
+		out.WriteString(b)
+	} else {
 		out.WriteString("]")
 	}
 
-	return nextIdx, isLatin, inQuot
+	return nextIdx, isLat, inQuo
 }
 
 // %
 func handleAddPunct(runes []rune, start int, out *bytes.Buffer, isLat bool, inQuo bool) (newIdx int, isLatin bool, inQuot bool) {
 	command, nextIdx := parseCommand(runes, start)
-
-	inQuot = inQuo
-	isLatin = isLat
-
-	switch command {
-	case "%":
-		out.WriteString("†")
-	case "%1":
-		out.WriteString("?")
-	case "%2":
-		out.WriteString("*")
-	case "%3":
-		out.WriteString("/")
-	case "%4":
-		out.WriteString("!")
-	case "%5":
-		out.WriteString("|")
-	case "%6":
-		out.WriteString("=")
-	case "%7":
-		out.WriteString("+")
-	case "%8":
-		out.WriteString("%")
-	case "%9":
-		out.WriteString("&")
-	case "%10":
-		out.WriteString(":")
-	case "%11":
-		out.WriteString("•")
-	case "%12":
-		out.WriteString("*")
-	case "%13":
-		out.WriteString("‡")
-	case "%14":
-		out.WriteString("§")
-	case "%18":
-		out.WriteString("'")
-	case "%19":
-		out.WriteString("-")
-	case "%41":
-		out.WriteString("-")
-	case "%43":
-		out.WriteString("×")
-	case "%103":
-		out.WriteString("\\")
-	case "%107":
-		out.WriteString("~")
-	default:
-	}
-
-	return nextIdx, isLatin, inQuot
+	out.WriteString(punctuationCodes[command[1:]])
+	return nextIdx, isLat, inQuo
 }
 
 // #
 func handleAddChar(runes []rune, start int, out *bytes.Buffer, isLat bool, inQuo bool) (newIdx int, isLatin bool, inQuot bool) {
 	command, nextIdx := parseCommand(runes, start)
-
-	inQuot = inQuo
-	isLatin = isLat
-
-	switch command {
-	case "#12":
-		out.WriteString("—")
-	case "#13":
-		out.WriteString("※")
-	case "#15":
-		out.WriteString(">")
-	case "#17":
-		out.WriteString("/")
-	case "#18":
-		out.WriteString("<")
-	default:
-	}
-
-	return nextIdx, isLatin, inQuot
+	out.WriteString(specialCodes[command[1:]])
+	return nextIdx, isLat, inQuo
 }
 
+// finalSigma matches a sigma at the end of a word: before a space, any
+// punctuation (quotation marks and brackets included) or the end.
+var finalSigma = regexp.MustCompile(`σ(\s|[[:punct:]]|\p{P}|$)`)
+
 func ToGreek(s string) string {
 	var out bytes.Buffer
 	upper := false
@@ -416,7 +293,7 @@ func ToGreek(s string) string {
 	}
 
 	res := out.String()
-	res = regexp.MustCompile(`σ(\s|[[:punct:],·]|$)`).ReplaceAllString(res, "ς$1")
+	res = finalSigma.ReplaceAllString(res, "ς$1")
 	res = NormalizeGreek(res)
 	return res
 }
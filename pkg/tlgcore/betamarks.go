@@ -0,0 +1,121 @@
+package tlgcore
+
+// The tables below map the Beta Code punctuation (%), special character
+// (#), bracket ([ ]) and quotation (") codes to Unicode, keyed by the
+// number after the code character ("" for the bare code). Codes not in
+// a table print nothing.
+
+// punctuationCodes are the % codes: additional punctuation and
+// critical signs.
+var punctuationCodes = map[string]string{
+	"":    "†", // crux
+	"1":   "?",
+	"2":   "*",
+	"3":   "/",
+	"4":   "!",
+	"5":   "|",
+	"6":   "=",
+	"7":   "+",
+	"8":   "%",
+	"9":   "&",
+	"10":  ":",
+	"11":  "•",
+	"12":  "*",
+	"13":  "‡", // double dagger
+	"14":  "§",
+	"15":  "ˈ",
+	"16":  "¦",
+	"17":  "‖",
+	"18":  "'",
+	"19":  "-",
+	"41":  "-",
+	"43":  "×",
+	"100": ";",
+	"101": "#",
+	"102": "‘",
+	"103": "\\",
+	"104": "^",
+	"105": "|||",
+	"106": "≈",
+	"107": "~",
+	"108": "±",
+	"109": "·",
+}
+
+// specialCodes are the # codes: the numeral signs, the letters used
+// only as numerals, metrical symbols and the critical signs of papyri
+// and manuscripts.
+var specialCodes = map[string]string{
+	"":   "ʹ", // numeral sign (keraia)
+	"1":  "ϟ", // koppa
+	"2":  "ϛ", // stigma
+	"3":  "ϙ", // archaic koppa
+	"5":  "ϡ", // sampi
+	"6":  "⊢",
+	"8":  "⸏", // paragraphos
+	"12": "—",
+	"13": "※",
+	"15": ">", // diple
+	"17": "/",
+	"18": "<", // reversed diple
+	"22": "͵", // lower numeral sign, for thousands
+	"50": "–", // long
+	"51": "⏑", // short (breve)
+	"52": "×", // anceps
+	"53": "⏓", // short over long
+	"54": "⏒", // long over short
+	"55": "⏔", // long over two shorts
+	"56": "⏕", // two shorts over long
+	"57": "⏖", // two shorts joined
+	"58": "⏗", // triseme
+	"59": "⏘", // tetraseme
+	"60": "⏙", // pentaseme
+	"70": "⸎", // coronis
+	"71": "⸐", // forked paragraphos
+	"72": "⸑", // reversed forked paragraphos
+	"73": "÷", // obelus
+	"74": "⁎", // asteriskos
+}
+
+// openBracketCodes and closeBracketCodes are the [ and ] codes.
+var (
+	openBracketCodes = map[string]string{
+		"":  "[",
+		"1": "(",
+		"2": "⟨",
+		"3": "{",
+		"4": "⟦",
+		"5": "⌊",
+		"6": "⌈",
+		"7": "⌈",
+		"8": "⌊",
+		"9": "˙",
+	}
+	closeBracketCodes = map[string]string{
+		"":  "]",
+		"1": ")",
+		"2": "⟩",
+		"3": "}",
+		"4": "⟧",
+		"5": "⌋",
+		"6": "⌉",
+		"7": "⌋",
+		"8": "⌉",
+		"9": "˙",
+	}
+)
+
+// quotationCodes are the quotation marks: the first string opens a
+// quotation, the second closes it. Marks with one form do neither, and
+// "8 only closes.
+var quotationCodes = map[string][2]string{
+	"":  {"“", "”"},
+	"1": {"„", ""},
+	"2": {"“", ""},
+	"3": {"‘", ""},
+	"4": {"‚", ""},
+	"5": {"’", ""},
+	"6": {"«", "»"},
+	"7": {"‹", "›"},
+	"8": {"", "”"},
+}
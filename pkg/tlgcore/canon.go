@@ -0,0 +1,428 @@
+package tlgcore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CanonAuthor is an author entry of the TLG canon (doccan1/doccan2).
+type CanonAuthor struct {
+	ID      string // e.g. "TLG0012"
+	Name    string
+	Epithet string // e.g. "Epic."
+	Date    string // e.g. "8 B.C."
+	Region  string // geographic epithet, e.g. "Ionia"
+	Genre   string
+	Works   map[string]*CanonWork // by work ID without zeros, e.g. "1"
+}
+
+// CanonWork is a work entry of the TLG canon.
+type CanonWork struct {
+	ID       string // e.g. "1"
+	Title    string
+	Date     string
+	Genre    string
+	Citation string // e.g. "Book/line"
+	Edition  string // bibliographic reference of the edition
+	Editor   string
+	Words    int // word count, 0 if unknown
+}
+
+// Canon holds the author and work entries of the TLG canon.
+type Canon struct {
+	Authors map[string]*CanonAuthor // by ID, e.g. "TLG0012"
+}
+
+// The canon files are TLG texts. Each entry starts with a heading and
+// goes on with labelled fields; ParseCanonLines finds the entries by
+// their citations, ParseCanon by the numbers of the headings:
+//
+//	0012 HOMERUS Epic.                 author heading
+//	Dat: 8 B.C.                        date (also Date:)
+//	Geo: Ionia                         region (also Geog:, Loc:)
+//	Gen: Epica                         genre (also Cla:)
+//	001 ILIAS                          work heading (also 0012 001 ILIAS)
+//	Cit: Book/line                     citation scheme
+//	Ed: T.W. Allen, Homeri Ilias, ...  edition (or a line naming an
+//	                                   editor with ed. or a year)
+//	(Cod: 115,477: Epic.)              word count and genre
+//
+// Labelled fields apply to the current work, or to the author before
+// the first work heading. Unrecognised lines are ignored.
+var (
+	canonAuthorRe  = regexp.MustCompile(`^(?:TLG|LAT)?\s*(\d{4})\s+([A-Za-z].*)$`)
+	canonWorkRe    = regexp.MustCompile(`^(?:(\d{4})\s+)?(\d{3})\s+([A-Za-z].*)$`)
+	canonFieldRe   = regexp.MustCompile(`^([A-Za-z]{2,7})\s*:\s*(.*)$`)
+	canonCodRe     = regexp.MustCompile(`\(\s*Cod\s*:\s*([\d,.]+)\s*:\s*([^)]*)\)`)
+	canonEditorRe  = regexp.MustCompile(`(?i)(?:^|\W)eds?\.,?\s+([^,;:]+)|^([^,]+?),\s+eds?\.`)
+	canonYearRe    = regexp.MustCompile(`\b1[5-9]\d\d\b|\b20\d\d\b`)
+	canonDateLike  = regexp.MustCompile(`(?i)\b(?:B\.\s?C\.|A\.\s?D\.|[ap]\.\s?Chr\.|saec\.|s\.\s?[IVX]+)`)
+	canonFieldKeys = map[string]string{
+		"dat": "date", "date": "date",
+		"geo": "region", "geog": "region", "loc": "region", "region": "region",
+		"gen": "genre", "genre": "genre", "cla": "genre", "class": "genre",
+		"cit": "citation",
+		"ed":  "edition", "edd": "edition", "edition": "edition",
+	}
+)
+
+// ParseCanon reads canon entries from text lines, finding authors and
+// works by their headings.
+func ParseCanon(lines []string) *Canon {
+	p := &canonParser{c: &Canon{Authors: make(map[string]*CanonAuthor)}}
+	for _, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if line == "" || p.continueEdition(line) {
+			continue
+		}
+		if m := canonWorkRe.FindStringSubmatch(line); m != nil && (p.author != nil || m[1] != "") && !canonDateLike.MatchString(m[3]) {
+			if m[1] != "" {
+				p.author = p.c.author("TLG" + m[1])
+			}
+			p.setWork(m[2])
+			p.work.Title = strings.TrimSpace(m[3])
+			continue
+		}
+		if m := canonAuthorRe.FindStringSubmatch(line); m != nil {
+			p.setAuthor(m[1])
+			p.author.Name, p.author.Epithet = splitEpithet(m[2])
+			continue
+		}
+		p.field(line)
+	}
+	return p.c
+}
+
+// ParseCanonLines reads canon entries from the lines of doccan1.txt or
+// doccan2.txt. On the disc each entry is cited by the author and work
+// it describes, in the a and b levels (Line.AuthorID and Line.WorkID;
+// work 0 is the author's own entry), and its first line is the heading,
+// with or without the number. These levels are used if the lines cite
+// more than one author; otherwise the headings are, as by ParseCanon.
+func ParseCanonLines(lines []*Line) *Canon {
+	authors := make(map[string]bool)
+	for _, l := range lines {
+		if isDigits(l.AuthorID) {
+			authors[l.AuthorID] = true
+		}
+	}
+	if len(authors) < 2 {
+		var text []string
+		for _, l := range lines {
+			text = append(text, l.Text)
+		}
+		return ParseCanon(text)
+	}
+
+	p := &canonParser{c: &Canon{Authors: make(map[string]*CanonAuthor)}}
+	for _, l := range lines {
+		line := strings.TrimSpace(l.Text)
+		if line == "" || !isDigits(l.AuthorID) {
+			continue
+		}
+		work := NormalizeID(l.WorkID)
+		if work == "0" || !isDigits(work) {
+			work = ""
+		}
+
+		heading := false
+		if p.author == nil || p.author.ID != canonAuthorID(l.AuthorID) {
+			p.setAuthor(l.AuthorID)
+			heading = work == ""
+		}
+		switch {
+		case work == "" && p.work != nil:
+			p.work = nil
+			heading = true
+		case work != "" && (p.work == nil || p.work.ID != work):
+			p.setWork(work)
+			heading = true
+		}
+
+		if heading && !canonFieldRe.MatchString(line) && !canonCodRe.MatchString(line) {
+			// The heading, e.g. "0012 HOMERUS Epic." or "001 ILIAS".
+			name := strings.TrimLeft(line, "0123456789 ")
+			if p.work != nil {
+				p.work.Title = name
+			} else {
+				p.author.Name, p.author.Epithet = splitEpithet(name)
+			}
+			continue
+		}
+		if !p.continueEdition(line) {
+			p.field(line)
+		}
+	}
+	return p.c
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// canonAuthorID turns an author number into a canon ID: "12" gives
+// "TLG0012".
+func canonAuthorID(num string) string {
+	n, _ := strconv.Atoi(num)
+	return fmt.Sprintf("TLG%04d", n)
+}
+
+// canonParser holds the entry the lines being read belong to.
+type canonParser struct {
+	c         *Canon
+	author    *CanonAuthor
+	work      *CanonWork
+	lastField string
+}
+
+func (p *canonParser) setAuthor(num string) {
+	p.author = p.c.author(canonAuthorID(num))
+	p.work = nil
+	p.lastField = ""
+}
+
+func (p *canonParser) setWork(id string) {
+	id = NormalizeID(id)
+	p.work = p.author.Works[id]
+	if p.work == nil {
+		p.work = &CanonWork{ID: id}
+		p.author.Works[id] = p.work
+	}
+	p.lastField = ""
+}
+
+// continueEdition adds a line to the edition if it starts with the year
+// that ends a wrapped edition reference.
+func (p *canonParser) continueEdition(line string) bool {
+	if p.work != nil && p.lastField == "edition" && canonYearRe.MatchString(line[:min(4, len(line))]) {
+		p.work.Edition += " " + line
+		return true
+	}
+	return false
+}
+
+// field reads a line of the current entry other than its heading.
+func (p *canonParser) field(line string) {
+	author, work := p.author, p.work
+	if author == nil {
+		return
+	}
+
+	if m := canonCodRe.FindStringSubmatch(line); m != nil {
+		genre := strings.TrimSpace(m[2])
+		if work != nil {
+			work.Words, _ = strconv.Atoi(strings.NewReplacer(",", "", ".", "").Replace(m[1]))
+			if work.Genre == "" {
+				work.Genre = genre
+			}
+		} else if author.Genre == "" {
+			author.Genre = genre
+		}
+		p.lastField = ""
+		return
+	}
+
+	if m := canonFieldRe.FindStringSubmatch(line); m != nil {
+		if key, ok := canonFieldKeys[strings.ToLower(m[1])]; ok {
+			setCanonField(author, work, key, strings.TrimSpace(m[2]))
+			p.lastField = key
+			return
+		}
+	}
+
+	switch {
+	case work != nil && p.lastField == "edition" && !canonDateLike.MatchString(line):
+		// A wrapped edition line.
+		work.Edition += " " + line
+		work.Editor = canonEditor(work.Edition)
+	case work != nil && work.Edition == "" && (canonEditorRe.MatchString(line) || canonYearRe.MatchString(line)):
+		setCanonField(author, work, "edition", line)
+		p.lastField = "edition"
+	case canonDateLike.MatchString(line):
+		setCanonField(author, work, "date", line)
+		p.lastField = ""
+	}
+}
+
+func (c *Canon) author(id string) *CanonAuthor {
+	a := c.Authors[id]
+	if a == nil {
+		a = &CanonAuthor{ID: id, Works: make(map[string]*CanonWork)}
+		c.Authors[id] = a
+	}
+	return a
+}
+
+func setCanonField(author *CanonAuthor, work *CanonWork, key, val string) {
+	if work == nil {
+		switch key {
+		case "date":
+			author.Date = val
+		case "region":
+			author.Region = val
+		case "genre":
+			author.Genre = val
+		}
+		return
+	}
+	switch key {
+	case "date":
+		work.Date = val
+	case "region":
+		if author.Region == "" {
+			author.Region = val
+		}
+	case "genre":
+		work.Genre = val
+	case "citation":
+		work.Citation = val
+	case "edition":
+		work.Edition = val
+		work.Editor = canonEditor(val)
+	}
+}
+
+// splitEpithet splits "HOMERUS Epic." into the name and the epithet,
+// which starts at the first abbreviated word.
+func splitEpithet(s string) (name, epithet string) {
+	words := strings.Fields(s)
+	for i, w := range words {
+		if strings.HasSuffix(w, ".") && i > 0 {
+			return strings.Join(words[:i], " "), strings.Join(words[i:], " ")
+		}
+	}
+	return strings.Join(words, " "), ""
+}
+
+// canonEditor returns the editor named in an edition reference: the
+// name after "ed." or before ", ed.", otherwise the part before the
+// first comma.
+func canonEditor(edition string) string {
+	if m := canonEditorRe.FindStringSubmatch(edition); m != nil {
+		return strings.TrimSpace(m[1] + m[2])
+	}
+	if name, _, ok := strings.Cut(edition, ","); ok {
+		return strings.TrimSpace(name)
+	}
+	return ""
+}
+
+// ReadCanon reads doccan1.txt and doccan2.txt of a TLG directory; the
+// entries of doccan2 complete those of doccan1. It fails only if
+// neither file can be read.
+func ReadCanon(dir string) (*Canon, error) {
+	var lines []*Line
+	var firstErr error
+	read := 0
+	for _, name := range []string{"doccan1.txt", "doccan2.txt"} {
+		p, err := OpenText(filepath.Join(dir, name))
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		// The canon is mostly in Roman letters; Greek is marked with $.
+		p.IsLatinFile = true
+		for l, err := range p.Lines() {
+			if err != nil {
+				break
+			}
+			lines = append(lines, l)
+		}
+		p.Close()
+		read++
+	}
+	if read == 0 {
+		return nil, firstErr
+	}
+	return ParseCanonLines(lines), nil
+}
+
+type cachedCanon struct {
+	modTime time.Time
+	canon   *Canon
+}
+
+var (
+	canonsMu sync.Mutex
+	canons   = make(map[string]cachedCanon)
+)
+
+// LoadCanon is ReadCanon with a cache: the canon of a directory is read
+// again only when one of its files changes.
+func LoadCanon(dir string) (*Canon, error) {
+	var modTime time.Time
+	for _, name := range []string{"doccan1.txt", "doccan2.txt"} {
+		if fi, err := os.Stat(filepath.Join(dir, name)); err == nil && fi.ModTime().After(modTime) {
+			modTime = fi.ModTime()
+		}
+	}
+
+	canonsMu.Lock()
+	defer canonsMu.Unlock()
+	if c, ok := canons[dir]; ok && c.modTime.Equal(modTime) {
+		return c.canon, nil
+	}
+	c, err := ReadCanon(dir)
+	if err != nil {
+		return nil, err
+	}
+	canons[dir] = cachedCanon{modTime, c}
+	return c, nil
+}
+
+// Author returns the entry of an author such as tlg0012, or nil.
+func (c *Canon) Author(id string) *CanonAuthor {
+	return c.Authors[normalizeAuthorID(id)]
+}
+
+// Work returns the entry of a work of an author, or nil.
+func (c *Canon) Work(author, work string) *CanonWork {
+	a := c.Author(author)
+	if a == nil {
+		return nil
+	}
+	return a.Works[NormalizeID(work)]
+}
+
+// Apply copies the canon data of an author's works into the metadata
+// read from the author's IDT file. Work fields left empty fall back to
+// those of the author.
+func (c *Canon) Apply(author string, meta map[string]*WorkMetadata) {
+	a := c.Author(author)
+	if a == nil {
+		return
+	}
+	for id, m := range meta {
+		m.Date, m.Genre = a.Date, a.Genre
+		if m.Genre == "" {
+			m.Genre = a.Epithet
+		}
+		w := a.Works[NormalizeID(id)]
+		if w == nil {
+			continue
+		}
+		m.Edition, m.Editor = w.Edition, w.Editor
+		if w.Date != "" {
+			m.Date = w.Date
+		}
+		if w.Genre != "" {
+			m.Genre = w.Genre
+		}
+	}
+}
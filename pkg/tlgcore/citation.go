@@ -0,0 +1,187 @@
+package tlgcore
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// splitComponent splits a citation component such as "327a" into its
+// numeric prefix and the remaining suffix. Components without a number
+// (e.g. "t" for titles) get -1 so they sort first.
+func splitComponent(c string) (int, string) {
+	i := 0
+	for i < len(c) && c[i] >= '0' && c[i] <= '9' {
+		i++
+	}
+	if i == 0 {
+		return -1, c
+	}
+	n, _ := strconv.Atoi(c[:i])
+	return n, c[i:]
+}
+
+func compareComponent(a, b string) int {
+	an, as := splitComponent(a)
+	bn, bs := splitComponent(b)
+	if an != bn {
+		if an < bn {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(as, bs)
+}
+
+// CompareCitation compares two dotted citations level by level. When
+// one is a prefix of the other, the shorter one sorts first, so "2"
+// stands for the start of book 2.
+func CompareCitation(a, b string) int {
+	ap := strings.Split(a, ".")
+	bp := strings.Split(b, ".")
+	for i := 0; i < len(ap) && i < len(bp); i++ {
+		if c := compareComponent(ap[i], bp[i]); c != 0 {
+			return c
+		}
+	}
+	return len(ap) - len(bp)
+}
+
+// compareCitationPrefix compares a citation with a bound, looking only
+// at as many levels as the bound has: "2.37" is equal to the bound "2".
+// Likewise a bound component without letters takes in the lettered
+// sections of its number: "328a" is equal to the bound "328".
+func compareCitationPrefix(cit, bound string) int {
+	cp := strings.Split(cit, ".")
+	bp := strings.Split(bound, ".")
+	for i := 0; i < len(bp); i++ {
+		if i >= len(cp) {
+			return -1
+		}
+		if c := compareBoundComponent(cp[i], bp[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareBoundComponent(c, bound string) int {
+	bn, bs := splitComponent(bound)
+	if bn < 0 || bs != "" {
+		return compareComponent(c, bound)
+	}
+	cn, _ := splitComponent(c)
+	switch {
+	case cn < bn:
+		return -1
+	case cn > bn:
+		return 1
+	}
+	return 0
+}
+
+// InRange reports whether a citation lies between from and to
+// inclusive. Empty bounds are open.
+func InRange(cit, from, to string) bool {
+	if from != "" && compareCitationPrefix(cit, from) < 0 {
+		return false
+	}
+	if to != "" && compareCitationPrefix(cit, to) > 0 {
+		return false
+	}
+	return true
+}
+
+// findSection returns the last section of a work that starts at or
+// before the citation cit, as compared by cmp, or nil if there is none.
+func findSection(meta *WorkMetadata, cit string, cmp func(a, b string) int) *Section {
+	var best *Section
+	for i := range meta.Sections {
+		sec := &meta.Sections[i]
+		if sec.Start == nil {
+			continue
+		}
+		start := FormatCitation(sec.Start, meta)
+		if start == "" || cmp(start, cit) > 0 {
+			continue
+		}
+		best = sec
+	}
+	return best
+}
+
+// ExtractRange returns the lines of a work whose citations lie between
+// from and to inclusive, e.g. ("2.100", "2.150") or ("327a", "328").
+// The IDT section index is used to start reading near from.
+func (p *Parser) ExtractRange(workID, from, to string) ([]*Line, error) {
+	targetID := NormalizeID(workID)
+	meta := p.IDTData[targetID]
+
+	var sec *Section
+	if meta != nil && from != "" {
+		sec = findSection(meta, from, CompareCitation)
+	}
+
+	var err error
+	switch {
+	case sec != nil:
+		err = p.SeekSection(meta, *sec)
+	case meta != nil:
+		err = p.SeekWork(targetID)
+	default:
+		p.ResetInternalState()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := p.collectRange(meta, targetID, from, to)
+	if err == nil && len(lines) == 0 && meta != nil {
+		// The IDT blocks may be unreliable; fall back to a full scan.
+		p.ResetInternalState()
+		lines, err = p.collectRange(nil, targetID, from, to)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("no lines of work %s between %q and %q", targetID, from, to)
+	}
+	return lines, nil
+}
+
+// collectRange reads the lines of a work in the range. Citations need
+// not increase through a work (scholia and fragments restart or go out
+// of order), so reading stops early only past the block of the last
+// IDT section of meta that starts within to; without sections the
+// whole work is read.
+func (p *Parser) collectRange(meta *WorkMetadata, targetID, from, to string) ([]*Line, error) {
+	var lines []*Line
+	lastBlock := -1
+	if meta != nil && to != "" {
+		if sec := findSection(meta, to, compareCitationPrefix); sec != nil {
+			lastBlock = sec.Block
+		}
+	}
+	found := false
+
+	for l, err := range p.Lines() {
+		if err != nil {
+			return lines, err
+		}
+		if l.WorkID != targetID {
+			if found {
+				break
+			}
+			continue
+		}
+		found = true
+		if lastBlock >= 0 && compareCitationPrefix(l.Citation, to) > 0 && p.block() > lastBlock {
+			break
+		}
+		if InRange(l.Citation, from, to) {
+			lines = append(lines, l)
+		}
+	}
+	return lines, nil
+}
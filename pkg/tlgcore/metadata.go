@@ -1,9 +1,7 @@
 package tlgcore
 
 import (
-	"fmt"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
 )
@@ -13,10 +11,25 @@ type CitationDef struct {
 	Label     string // e.g. "Book", "Line"
 }
 
+// Section is an IDT Type 3 entry: the block where a section of a work
+// starts and the citation of its first line (Type 8).
+type Section struct {
+	Block int
+	Start map[string]IDState
+}
+
 type WorkMetadata struct {
 	ID        string
 	Title     string
 	Citations []CitationDef
+	Block     int // first 8 KB block of the work in the .txt file
+	Sections  []Section
+
+	// From the TLG canon (see Canon.Apply); empty if unknown.
+	Edition string
+	Editor  string
+	Date    string
+	Genre   string
 }
 
 func ReadIDT(path string) (map[string]*WorkMetadata, error) {
@@ -28,6 +41,8 @@ func ReadIDT(path string) (map[string]*WorkMetadata, error) {
 
 	pos := 0
 	var currentWork *WorkMetadata
+	var currentSection *Section
+	var sectionState *Parser // running citation state of the current work
 
 	// consumeID reads a sequence of bytes as long as they have the high bit set.
 	// TLG IDT files use high-bit bytes for ID data to distinguish from Type codes.
@@ -39,6 +54,13 @@ func ReadIDT(path string) (map[string]*WorkMetadata, error) {
 		return data[start:pos]
 	}
 
+	// readBlock reads a 2-byte big-endian block number.
+	readBlock := func() int {
+		v := int(data[pos])<<8 | int(data[pos+1])
+		pos += 2
+		return v
+	}
+
 	for pos < len(data) {
 		// Read Type Code
 		typ := data[pos]
@@ -53,7 +75,8 @@ func ReadIDT(path string) (map[string]*WorkMetadata, error) {
 			if pos+4 > len(data) {
 				break
 			}
-			pos += 4 // Skip Len and Block
+			pos += 2 // Skip Len
+			readBlock()
 			consumeID()
 
 		case 2: // New Work (Type 2)
@@ -61,13 +84,16 @@ func ReadIDT(path string) (map[string]*WorkMetadata, error) {
 			if pos+4 > len(data) {
 				break
 			}
-			pos += 4 // Skip Len and Block
+			pos += 2 // Skip Len
+			block := readBlock()
 
 			// The ID String here contains the Work ID (Level b)
 			idBytes := consumeID()
 
 			idStr := DecodeWorkID(idBytes)
-			currentWork = &WorkMetadata{ID: idStr}
+			currentWork = &WorkMetadata{ID: idStr, Block: block}
+			currentSection = nil
+			sectionState = NewParser(nil)
 
 			if idStr != "" {
 				m[idStr] = currentWork
@@ -78,9 +104,23 @@ func ReadIDT(path string) (map[string]*WorkMetadata, error) {
 			if pos+2 > len(data) {
 				break
 			}
-			pos += 2
+			block := readBlock()
+			if currentWork != nil {
+				currentWork.Sections = append(currentWork.Sections, Section{Block: block})
+				currentSection = &currentWork.Sections[len(currentWork.Sections)-1]
+			}
 
-		case 8, 9, 10, 12, 13: // ID Fields
+		case 8: // Starting citation of the section
+			idBytes := consumeID()
+			if sectionState == nil {
+				break
+			}
+			start := sectionState.applyID(idBytes)
+			if currentSection != nil && currentSection.Start == nil {
+				currentSection.Start = start
+			}
+
+		case 9, 10, 12, 13: // ID Fields
 			consumeID()
 
 		case 11: // Start Exception (Type 11)
@@ -242,7 +282,7 @@ func DecodeWorkID(b []byte) string {
 			numVal = readBin(1)
 		case 0x9:
 			numVal = readBin(1)
-			strVal = string(readBin(1)) // effectively readChar
+			strVal = string(rune(readBin(1))) // effectively readChar
 		case 0xA:
 			numVal = readBin(1)
 			strVal = readStr()
@@ -250,12 +290,12 @@ func DecodeWorkID(b []byte) string {
 			numVal = readBin(2)
 		case 0xC:
 			numVal = readBin(2)
-			strVal = string(readBin(1))
+			strVal = string(rune(readBin(1)))
 		case 0xD:
 			numVal = readBin(2)
 			strVal = readStr()
 		case 0xE:
-			strVal = string(readBin(1)) // readChar
+			strVal = string(rune(readBin(1))) // readChar
 		case 0xF:
 			strVal = readStr()
 		}
@@ -299,24 +339,15 @@ func decodeSimpleASCII(b []byte) string {
 	return res
 }
 
+// GetAuthorName returns the name of the author of a file such as
+// tlg0012 from authtab.dir, the ID itself if the table lacks it.
 func GetAuthorName(path, tlgID string) string {
-	var prefixID string
-	data, err := os.ReadFile(path)
-	if err != nil {
+	if len(tlgID) < 3 {
 		return "Unknown"
 	}
-
-	if len(tlgID) >= 3 {
-		prefixID = strings.ToUpper(tlgID[:3])
-	} else {
+	t, err := LoadAuthorTable(path)
+	if err != nil {
 		return "Unknown"
 	}
-
-	cleanID := fmt.Sprintf("%s%04s", prefixID, strings.TrimPrefix(strings.ToUpper(tlgID), prefixID))
-	re := regexp.MustCompile(fmt.Sprintf(`(?s)%s.*?&1(.*?)&`, cleanID))
-	matches := re.FindSubmatch(data)
-	if len(matches) > 1 {
-		return strings.TrimSpace(strings.Split(string(matches[1]), "&")[0])
-	}
-	return tlgID
+	return t.Name(tlgID)
 }
@@ -5,14 +5,19 @@ import (
 	"strings"
 )
 
+var (
+	latinMarks  = regexp.MustCompile(`[\^_\d\#]`)
+	strictMarks = regexp.MustCompile(`[/\(\)\\=\|\+\^_\d]`)
+	vowelFuzzer = strings.NewReplacer("e", "a", "h", "a", "o", "a", "w", "a")
+)
+
 func NormalizeLatin(s string) string {
 	fields := strings.Fields(s)
 	if len(fields) == 0 {
 		return ""
 	}
 	s = fields[0]
-	re := regexp.MustCompile(`[\^_\d\#]`)
-	return strings.ToLower(re.ReplaceAllString(s, ""))
+	return strings.ToLower(latinMarks.ReplaceAllString(s, ""))
 }
 
 func NormalizeStrict(s string) string {
@@ -21,13 +26,11 @@ func NormalizeStrict(s string) string {
 		return ""
 	}
 	s = fields[0]
-	re := regexp.MustCompile(`[/\(\)\\=\|\+\^_\d]`)
-	return strings.ToLower(re.ReplaceAllString(s, ""))
+	return strings.ToLower(strictMarks.ReplaceAllString(s, ""))
 }
 
 func NormalizeFuzzy(s string) string {
 	s = NormalizeStrict(s)
-	vowelFuzzer := strings.NewReplacer("e", "a", "h", "a", "o", "a", "w", "a")
 	return vowelFuzzer.Replace(s)
 }
 
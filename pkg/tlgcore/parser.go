@@ -3,7 +3,9 @@ package tlgcore
 import (
 	"fmt"
 	"io"
+	"iter"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -21,6 +23,30 @@ type IDState struct {
 	Active bool
 }
 
+// String returns the citation value of a level, e.g. "2", "327a".
+func (st IDState) String() string {
+	s := st.ASCII
+	if st.Binary > 0 {
+		if len(st.ASCII) == 1 && st.ASCII[0] >= 'a' && st.ASCII[0] <= 'e' && st.Binary < 10 {
+			s = string(st.ASCII[0] + byte(st.Binary))
+		} else {
+			s = strconv.Itoa(st.Binary) + st.ASCII
+		}
+	}
+	return s
+}
+
+// Line is a single text segment of a TLG/PHI file together with the
+// citation state that was in effect when it was read.
+type Line struct {
+	AuthorID string
+	WorkID   string
+	Levels   map[string]IDState // snapshot of a,b,c,d,n,v-z
+	Citation string             // formatted citation, e.g. "1.23"
+	Beta     string             // raw Beta Code
+	Text     string             // Unicode (Greek or Latin)
+}
+
 type Parser struct {
 	File        *os.File
 	Levels      map[string]*IDState
@@ -30,6 +56,8 @@ type Parser struct {
 
 	IDTData     map[string]*WorkMetadata
 	CurrentMeta *WorkMetadata
+
+	limit int // number of valid bytes in Buffer
 }
 
 func NewParser(f *os.File) *Parser {
@@ -44,6 +72,41 @@ func NewParser(f *os.File) *Parser {
 	return p
 }
 
+// IsLatinName reports whether a corpus file name (e.g. lat0474.txt)
+// holds Latin rather than Greek text.
+func IsLatinName(name string) bool {
+	base := strings.ToUpper(filepath.Base(name))
+	for _, pref := range []string{"LAT", "CIV", "PHI"} {
+		if strings.HasPrefix(base, pref) {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenText opens a corpus text file together with its IDT file. A
+// missing IDT file is not an error; IDTData is then empty.
+func OpenText(path string) (*Parser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	idtData, err := ReadIDT(strings.TrimSuffix(path, filepath.Ext(path)) + ".idt")
+	if err != nil {
+		idtData = make(map[string]*WorkMetadata)
+	}
+
+	p := NewParser(f)
+	p.IDTData = idtData
+	p.IsLatinFile = IsLatinName(path)
+	return p, nil
+}
+
+func (p *Parser) Close() error {
+	return p.File.Close()
+}
+
 func (p *Parser) ProcessText(s string) string {
 	if p.IsLatinFile {
 		return ToLatin(s)
@@ -54,112 +117,247 @@ func (p *Parser) ProcessText(s string) string {
 func (p *Parser) ResetInternalState() {
 	p.File.Seek(0, 0)
 	p.Pos = 0
+	p.limit = 0
 	for k := range levelRank {
 		p.Levels[k] = &IDState{}
 	}
 }
 
-func (p *Parser) ExtractList(idtData map[string]*WorkMetadata) ([]string, error) {
-	p.ResetInternalState()
+// SeekBlock positions the parser at the start of an 8 KB block and
+// clears the citation state.
+func (p *Parser) SeekBlock(block int) error {
+	if _, err := p.File.Seek(int64(block)*BlockSize, io.SeekStart); err != nil {
+		return err
+	}
+	p.Pos = 0
+	p.limit = 0
+	for k := range levelRank {
+		p.Levels[k] = &IDState{}
+	}
+	return nil
+}
 
-	seenWorks := make(map[string]bool)
-	var results []string
+// block returns the number of the block the parser is reading.
+func (p *Parser) block() int {
+	off, err := p.File.Seek(0, io.SeekCurrent)
+	if err != nil || off == 0 {
+		return 0
+	}
+	return int((off - 1) / BlockSize)
+}
 
-	for {
-		n, err := p.File.Read(p.Buffer)
-		if n == 0 || err == io.EOF {
+// SeekWork positions the parser at the block where a work starts,
+// using the block numbers from the IDT file.
+func (p *Parser) SeekWork(workID string) error {
+	meta := p.IDTData[NormalizeID(workID)]
+	if meta == nil {
+		return fmt.Errorf("work ID %s not in IDT", workID)
+	}
+	if err := p.SeekBlock(meta.Block); err != nil {
+		return err
+	}
+	p.seedWork(meta)
+	return nil
+}
+
+// SeekSection positions the parser at the block where a section of a
+// work starts. The citation state is seeded from the section's starting
+// citation in case the block does not restate it.
+func (p *Parser) SeekSection(meta *WorkMetadata, sec Section) error {
+	if err := p.SeekBlock(sec.Block); err != nil {
+		return err
+	}
+	p.seedWork(meta)
+	for k, st := range sec.Start {
+		if k != "a" && k != "b" {
+			*p.Levels[k] = st
+		}
+	}
+	return nil
+}
+
+func (p *Parser) seedWork(meta *WorkMetadata) {
+	p.Levels["b"] = &IDState{ASCII: meta.ID, Active: true}
+}
+
+// applyID runs a sequence of ID bytes through the level state machine
+// and returns a snapshot of the resulting levels.
+func (p *Parser) applyID(b []byte) map[string]IDState {
+	p.Buffer = b
+	p.Pos = 0
+	for p.Pos < len(p.Buffer) {
+		if p.parseIDByte() {
 			break
 		}
-		p.Pos = 0
-
-		for p.Pos < n {
-			b := p.Buffer[p.Pos]
-			if b&0x80 != 0 {
-				if p.parseIDByte() {
-					break
-				}
-				continue
-			}
+	}
+	levels := make(map[string]IDState, len(p.Levels))
+	for k, st := range p.Levels {
+		levels[k] = *st
+	}
+	return levels
+}
 
-			_ = p.readText(n)
+// fill reads the next block into Buffer.
+func (p *Parser) fill() error {
+	n, err := io.ReadFull(p.File, p.Buffer)
+	if n == 0 {
+		if err == nil || err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return err
+	}
+	p.Pos = 0
+	p.limit = n
+	return nil
+}
 
-			workState := p.Levels["b"]
-			if !workState.Active {
-				continue
+// Next returns the next non-empty text segment of the file, or io.EOF
+// when the file is exhausted. Segments outside of any work are skipped.
+func (p *Parser) Next() (*Line, error) {
+	for {
+		if p.Pos >= p.limit {
+			if err := p.fill(); err != nil {
+				return nil, err
 			}
+			continue
+		}
 
-			currentID := p.getCurrentWorkID()
-			if currentID == "0" {
-				continue
+		b := p.Buffer[p.Pos]
+		if b&0x80 != 0 {
+			if p.parseIDByte() {
+				// End of block or file: skip the padding.
+				p.Pos = p.limit
 			}
+			continue
+		}
+
+		text := p.readText(p.limit)
+		if len(text) == 0 {
+			continue
+		}
+
+		if !p.Levels["b"].Active {
+			continue
+		}
 
-			if !seenWorks[currentID] {
-				seenWorks[currentID] = true
-				title := "(Unknown Title)"
-				if meta, ok := idtData[currentID]; ok {
-					title = meta.Title
-				}
-				line := fmt.Sprintf("ID:%-4s | %s", currentID, title)
-				results = append(results, line)
+		return p.newLine(text), nil
+	}
+}
+
+// Lines iterates over the text segments from the current position,
+// stopping after the first error other than io.EOF.
+func (p *Parser) Lines() iter.Seq2[*Line, error] {
+	return func(yield func(*Line, error) bool) {
+		for {
+			l, err := p.Next()
+			if err == io.EOF {
+				return
+			}
+			if !yield(l, err) || err != nil {
+				return
 			}
 		}
 	}
-	return results, nil
 }
 
-func (p *Parser) ExtractWork(targetWorkID string) (string, error) {
+func (p *Parser) newLine(beta string) *Line {
+	workID := p.getCurrentWorkID()
+	if p.IDTData != nil {
+		p.CurrentMeta = p.IDTData[workID]
+	}
+
+	l := &Line{
+		AuthorID: p.Levels["a"].String(),
+		WorkID:   workID,
+		Levels:   make(map[string]IDState, len(p.Levels)),
+		Beta:     beta,
+		Text:     p.ProcessText(beta),
+	}
+	for k, st := range p.Levels {
+		l.Levels[k] = *st
+	}
+	l.Citation = FormatCitation(l.Levels, p.CurrentMeta)
+	return l
+}
+
+func (p *Parser) ExtractList(idtData map[string]*WorkMetadata) ([]string, error) {
 	p.ResetInternalState()
 
-	if p.IDTData != nil {
-		p.CurrentMeta = p.IDTData[targetWorkID]
+	seenWorks := make(map[string]bool)
+	var results []string
+
+	for l, err := range p.Lines() {
+		if err != nil {
+			return results, err
+		}
+		if l.WorkID == "0" || seenWorks[l.WorkID] {
+			continue
+		}
+		seenWorks[l.WorkID] = true
+		title := "(Unknown Title)"
+		if meta, ok := idtData[l.WorkID]; ok {
+			title = meta.Title
+		}
+		results = append(results, fmt.Sprintf("ID:%-4s | %s", l.WorkID, title))
 	}
+	return results, nil
+}
 
-	var sb strings.Builder
-	targetInt, _ := strconv.Atoi(targetWorkID)
+// FormatLine renders a line the way ExtractWork prints it.
+func FormatLine(l *Line) string {
+	return fmt.Sprintf("%-10s %s\n", l.Citation, l.Text)
+}
+
+// WorkLines returns the lines of a single work. When the IDT data
+// knows where the work starts, the parser seeks straight to its block.
+func (p *Parser) WorkLines(targetWorkID string) ([]*Line, error) {
+	targetID := NormalizeID(targetWorkID)
+	if _, ok := p.IDTData[targetID]; ok {
+		if err := p.SeekWork(targetID); err == nil {
+			if lines, err := p.collectWork(targetID); err == nil {
+				return lines, nil
+			}
+		}
+	}
+
+	p.ResetInternalState()
+	return p.collectWork(targetID)
+}
+
+// collectWork reads lines from the current position until the work
+// with targetID has been passed.
+func (p *Parser) collectWork(targetID string) ([]*Line, error) {
+	var lines []*Line
 	found := false
 
-	for {
-		n, err := p.File.Read(p.Buffer)
-		if n == 0 || err == io.EOF {
+	for l, err := range p.Lines() {
+		if err != nil {
+			return lines, err
+		}
+		if l.WorkID == targetID {
+			found = true
+			lines = append(lines, l)
+		} else if found {
 			break
 		}
-		p.Pos = 0
-
-		for p.Pos < n {
-			b := p.Buffer[p.Pos]
-			if b&0x80 != 0 {
-				if p.parseIDByte() {
-					break
-				}
-				continue
-			}
-
-			text := p.readText(n)
-			if len(text) == 0 {
-				continue
-			}
+	}
 
-			workState := p.Levels["b"]
-			if !workState.Active {
-				continue
-			}
+	if !found {
+		return nil, fmt.Errorf("work ID %s not found", targetID)
+	}
+	return lines, nil
+}
 
-			currentID := p.getCurrentWorkID()
-			currentInt := 0
-			if val, err := strconv.Atoi(currentID); err == nil {
-				currentInt = val
-			}
+func (p *Parser) ExtractWork(targetWorkID string) (string, error) {
+	lines, err := p.WorkLines(targetWorkID)
+	if err != nil {
+		return "", err
+	}
 
-			if currentID == targetWorkID || currentInt == targetInt {
-				found = true
-				output := p.ProcessText(text)
-				if strings.TrimSpace(output) != "" {
-					cit := p.formatCitation()
-					sb.WriteString(fmt.Sprintf("%-10s %s\n", cit, output))
-				}
-			} else if found {
-				return sb.String(), nil
-			}
+	var sb strings.Builder
+	for _, l := range lines {
+		if strings.TrimSpace(l.Text) != "" {
+			sb.WriteString(FormatLine(l))
 		}
 	}
 
@@ -361,12 +559,14 @@ func (p *Parser) readStr() string {
 	return sb.String()
 }
 
-func (p *Parser) formatCitation() string {
+// FormatCitation joins the active citation levels of a work, in the
+// order given by its IDT citation labels, e.g. "2.100" or "327a.3".
+func FormatCitation(levels map[string]IDState, meta *WorkMetadata) string {
 	var pts []string
 	var levelsToCheck []string
 
-	if p.CurrentMeta != nil && len(p.CurrentMeta.Citations) > 0 {
-		for _, def := range p.CurrentMeta.Citations {
+	if meta != nil && len(meta.Citations) > 0 {
+		for _, def := range meta.Citations {
 			levelsToCheck = append(levelsToCheck, def.LevelChar)
 		}
 	} else {
@@ -374,27 +574,18 @@ func (p *Parser) formatCitation() string {
 	}
 
 	for _, l := range levelsToCheck {
-		st := p.Levels[l]
-		if st == nil || !st.Active {
+		st, ok := levels[l]
+		if !ok || !st.Active {
 			continue
 		}
 
-		s := st.ASCII
-		if st.Binary > 0 {
-			if len(st.ASCII) == 1 && st.ASCII[0] >= 'a' && st.ASCII[0] <= 'e' && st.Binary < 10 {
-				s = string(st.ASCII[0] + byte(st.Binary))
-			} else {
-				s = strconv.Itoa(st.Binary) + st.ASCII
-			}
-		}
-
-		if s != "" {
+		if s := st.String(); s != "" {
 			pts = append(pts, s)
 		}
 	}
 
-	if len(pts) == 0 && p.Levels["z"].Active {
-		return p.Levels["z"].ASCII
+	if len(pts) == 0 && levels["z"].Active {
+		return levels["z"].ASCII
 	}
 	return strings.Join(pts, ".")
 }
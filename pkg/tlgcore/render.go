@@ -0,0 +1,86 @@
+package tlgcore
+
+import (
+	"html"
+	"strings"
+)
+
+// htmlTags are the elements HTML wraps around styled text, outermost
+// first.
+var htmlTags = []struct {
+	style      Style
+	start, end string
+}{
+	{Marginal, `<span class="marginal">`, `</span>`},
+	{Speaker, `<span class="speaker">`, `</span>`},
+	{Title, `<cite>`, `</cite>`},
+	{Lacuna, `<span class="lacuna">`, `</span>`},
+	{Bold, `<b>`, `</b>`},
+	{Italic, `<i>`, `</i>`},
+	{Superscript, `<sup>`, `</sup>`},
+	{Subscript, `<sub>`, `</sub>`},
+}
+
+// HTML renders spans as an HTML fragment. Brackets and quotation marks
+// are kept as text; fonts, titles, speakers, marginal notes and
+// lacunae become elements or classes.
+func HTML(spans []Span) string {
+	var b strings.Builder
+	for _, s := range spans {
+		text := html.EscapeString(s.Text)
+		if s.Style&Sign != 0 {
+			b.WriteString(text)
+			continue
+		}
+		for _, t := range htmlTags {
+			if s.Style&t.style != 0 {
+				b.WriteString(t.start)
+			}
+		}
+		b.WriteString(text)
+		for i := len(htmlTags) - 1; i >= 0; i-- {
+			if s.Style&htmlTags[i].style != 0 {
+				b.WriteString(htmlTags[i].end)
+			}
+		}
+	}
+	return b.String()
+}
+
+// sgrCodes are the terminal attributes of the styles: bold for bold
+// and speakers, italic for italics and titles, faint for marginal
+// notes and lacunae. Terminals cannot raise or lower text.
+var sgrCodes = []struct {
+	style Style
+	code  string
+}{
+	{Bold | Speaker, "1"},
+	{Marginal | Lacuna, "2"},
+	{Italic | Title, "3"},
+}
+
+// SGR returns the ANSI Select Graphic Rendition parameters for a
+// style, e.g. "1;3", or "" for plain text.
+func SGR(st Style) string {
+	var codes []string
+	for _, c := range sgrCodes {
+		if st&Sign == 0 && st&c.style != 0 {
+			codes = append(codes, c.code)
+		}
+	}
+	return strings.Join(codes, ";")
+}
+
+// ANSI renders spans for a terminal, with escape sequences for bold,
+// italic and faint text.
+func ANSI(spans []Span) string {
+	var b strings.Builder
+	for _, s := range spans {
+		if sgr := SGR(s.Style); sgr != "" {
+			b.WriteString("\x1b[" + sgr + "m" + s.Text + "\x1b[0m")
+		} else {
+			b.WriteString(s.Text)
+		}
+	}
+	return b.String()
+}
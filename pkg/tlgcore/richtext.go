@@ -0,0 +1,228 @@
+package tlgcore
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+)
+
+// Style is a set of text attributes given by Beta Code formatting and
+// markup codes, which ToGreek and ToLatin drop.
+type Style uint32
+
+const (
+	Bold        Style = 1 << iota // $1, &1; $4, &4 with Italic
+	Italic                        // $3, &3
+	Superscript                   // $6, &6, <6 >6
+	Subscript                     // $7, &7, <7 >7
+	Title                         // {1 }1: title of a work
+	Speaker                       // { }: name of a speaker in drama
+	Marginal                      // {2 }2: marginal note
+	Lacuna                        // [ ] holding only dots or dashes
+	Supplied                      // [ ]: lost, restored by the editor
+	Omitted                       // [2 ]2: omitted, added by the editor
+	Surplus                       // [3 ]3: deleted by the editor
+	Erased                        // [4 ]4: erased in the source
+	Quote                         // " and "1, "2, "6, "7, "8
+	Sign                          // the bracket or quotation mark itself
+)
+
+// fontStyles are the styles of the font codes after $ and &.
+var fontStyles = map[string]Style{
+	"1": Bold,
+	"2": Bold | Italic,
+	"3": Italic,
+	"4": Bold | Italic,
+	"6": Superscript,
+	"7": Subscript,
+}
+
+const fontMask = Bold | Italic | Superscript | Subscript
+
+// markupStyles are the styles of the codes after { and }, and those of
+// the quasi-brackets < and >.
+var (
+	markupStyles  = map[string]Style{"": Speaker, "1": Title, "2": Marginal}
+	formatStyles  = map[string]Style{"6": Superscript, "7": Subscript}
+	bracketStyles = map[string]Style{"": Supplied, "2": Omitted, "3": Surplus, "4": Erased}
+)
+
+// Span is a run of text with the same style. Text is Unicode, as
+// produced by ToGreek or ToLatin.
+type Span struct {
+	Text  string
+	Style Style
+	Latin bool // whether the text is Latin (else Greek)
+}
+
+// SpanReader turns lines of Beta Code into spans. Styles carry over
+// from one line to the next, as in the files: a quotation or a title
+// may run over several lines. Use one SpanReader per passage.
+type SpanReader struct {
+	LatinFile bool
+	latin     bool
+	style     Style
+	supplied  int // index in the line's spans where [ opened, or -1
+}
+
+// NewSpanReader returns a SpanReader for a Greek or a Latin file.
+func NewSpanReader(latinFile bool) *SpanReader {
+	return &SpanReader{LatinFile: latinFile, latin: latinFile, supplied: -1}
+}
+
+// Spans converts a line of Beta Code.
+func (r *SpanReader) Spans(beta string) []Span {
+	var spans []Span
+	var run strings.Builder
+	runes := []rune(beta)
+	r.supplied = -1
+
+	add := func(s Span) {
+		if s.Text == "" {
+			return
+		}
+		if n := len(spans); n > 0 && s.Style&Sign == 0 && spans[n-1].Style == s.Style && spans[n-1].Latin == s.Latin {
+			spans[n-1].Text += s.Text
+			return
+		}
+		spans = append(spans, s)
+	}
+	flush := func(next int) {
+		if run.Len() == 0 {
+			return
+		}
+		s := run.String()
+		run.Reset()
+		if r.latin {
+			s = ToLatin(s)
+		} else {
+			s = ToGreek(s)
+			// A word broken by a code does not end at the break.
+			if next < len(runes) && unicode.IsLetter(runes[next]) && strings.HasSuffix(s, "ς") {
+				s = strings.TrimSuffix(s, "ς") + "σ"
+			}
+		}
+		add(Span{s, r.style, r.latin})
+	}
+	// sign adds the mark the handler of ToGreek prints for a code;
+	// inQuot tells a quotation mark whether it closes a quotation.
+	sign := func(i int, inQuot bool) {
+		var out bytes.Buffer
+		bcmHandlers[runes[i]](runes, i, &out, r.latin, inQuot)
+		add(Span{out.String(), r.style | Sign, r.latin})
+	}
+
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		if !strings.ContainsRune(`$&{}<>"[]`, c) {
+			run.WriteRune(c)
+			continue
+		}
+		j := i + 1
+		for j < len(runes) && unicode.IsDigit(runes[j]) {
+			j++
+		}
+		code := string(runes[i+1 : j])
+		known := true
+
+		switch c {
+		case '$', '&':
+			flush(j)
+			r.latin = c == '&'
+			r.style = r.style&^fontMask | fontStyles[code]
+		case '{', '}':
+			flush(j)
+			if code == "70" {
+				// TLG editorial text, in Latin.
+				r.latin = c == '{' || r.LatinFile
+			} else if c == '{' {
+				r.style |= markupStyles[code]
+			} else {
+				r.style &^= markupStyles[code]
+			}
+		case '<', '>':
+			if c == '>' && code == "" {
+				known = false
+				break
+			}
+			flush(j)
+			if c == '<' {
+				r.style |= formatStyles[code]
+			} else {
+				r.style &^= formatStyles[code]
+			}
+		case '"':
+			switch code {
+			case "", "1", "2", "6", "7":
+				flush(j)
+				if r.style&Quote == 0 {
+					sign(i, false)
+					r.style |= Quote
+				} else {
+					r.style &^= Quote
+					sign(i, true)
+				}
+			case "8":
+				flush(j)
+				r.style &^= Quote
+				sign(i, true)
+			default:
+				known = false
+			}
+		case '[', ']':
+			st, ok := bracketStyles[code]
+			if !ok {
+				known = false
+				break
+			}
+			flush(j)
+			if c == '[' {
+				sign(i, r.style&Quote != 0)
+				r.style |= st
+				if st == Supplied {
+					r.supplied = len(spans)
+				}
+			} else {
+				if st == Supplied {
+					r.markLacuna(spans)
+				}
+				r.style &^= st
+				sign(i, r.style&Quote != 0)
+			}
+		}
+		if !known {
+			// Left to ToGreek and ToLatin.
+			run.WriteString(string(runes[i:j]))
+		}
+		i = j - 1
+	}
+	flush(len(runes))
+	return spans
+}
+
+// markLacuna marks the text since the last [ as a lacuna if it holds
+// only the dots or dashes that stand for lost letters.
+func (r *SpanReader) markLacuna(spans []Span) {
+	if r.supplied < 0 || r.supplied >= len(spans) {
+		return
+	}
+	for _, s := range spans[r.supplied:] {
+		if s.Style&Sign == 0 && strings.Trim(s.Text, " .-–—…") != "" {
+			return
+		}
+	}
+	for i := r.supplied; i < len(spans); i++ {
+		spans[i].Style = spans[i].Style&^Supplied | Lacuna
+	}
+	r.supplied = -1
+}
+
+// PlainText joins the text of spans, which is about what ToGreek or
+// ToLatin makes of the line.
+func PlainText(spans []Span) string {
+	var b strings.Builder
+	for _, s := range spans {
+		b.WriteString(s.Text)
+	}
+	return b.String()
+}
@@ -0,0 +1,130 @@
+package tlgcore
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// URN is a CTS URN such as urn:cts:greekLit:tlg0012.tlg001:1.1-1.10.
+type URN struct {
+	Namespace string // "greekLit" or "latinLit"
+	TextGroup string // e.g. "tlg0012", "phi0474"
+	Work      string // e.g. "tlg001"
+	Version   string // optional, e.g. "perseus-grc2"
+	From      string // passage start, may be empty
+	To        string // passage end, empty for a single reference
+}
+
+func ParseURN(s string) (*URN, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) < 4 || !strings.EqualFold(parts[0], "urn") || !strings.EqualFold(parts[1], "cts") {
+		return nil, fmt.Errorf("not a CTS URN: %s", s)
+	}
+
+	u := &URN{Namespace: parts[2]}
+
+	work := strings.Split(parts[3], ".")
+	u.TextGroup = work[0]
+	if len(work) > 1 {
+		u.Work = work[1]
+	}
+	if len(work) > 2 {
+		u.Version = work[2]
+	}
+	if u.TextGroup == "" || u.Work == "" {
+		return nil, fmt.Errorf("CTS URN without text group and work: %s", s)
+	}
+
+	if len(parts) > 4 {
+		passage := strings.Join(parts[4:], ":")
+		from, to, _ := strings.Cut(passage, "-")
+		u.From = stripSubref(from)
+		u.To = stripSubref(to)
+	}
+	return u, nil
+}
+
+// stripSubref removes a CTS subreference ("1.1@μῆνιν") from a passage.
+func stripSubref(s string) string {
+	s, _, _ = strings.Cut(s, "@")
+	return s
+}
+
+func (u *URN) String() string {
+	s := fmt.Sprintf("urn:cts:%s:%s.%s", u.Namespace, u.TextGroup, u.Work)
+	if u.Version != "" {
+		s += "." + u.Version
+	}
+	if u.From != "" {
+		s += ":" + u.From
+		if u.To != "" && u.To != u.From {
+			s += "-" + u.To
+		}
+	}
+	return s
+}
+
+// FileName returns the corpus file of the text group: tlg0012 maps to
+// tlg0012.txt, phi0474 to lat0474.txt.
+func (u *URN) FileName() string {
+	tg := strings.ToLower(u.TextGroup)
+	if strings.HasPrefix(tg, "phi") {
+		tg = "lat" + tg[3:]
+	}
+	return tg + ".txt"
+}
+
+// Path returns the corpus file of the text group inside dir.
+func (u *URN) Path(dir string) string {
+	return filepath.Join(dir, u.FileName())
+}
+
+// WorkID returns the work number as used by the parser ("tlg001" -> "1").
+func (u *URN) WorkID() string {
+	return NormalizeID(strings.TrimLeft(u.Work, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"))
+}
+
+// NewURN builds the URN of a work in a corpus file such as tlg0012.txt.
+func NewURN(fileName, workID string) *URN {
+	base := strings.ToLower(strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName)))
+
+	u := &URN{Namespace: "greekLit"}
+	prefix := "tlg"
+	if strings.HasPrefix(base, "lat") || strings.HasPrefix(base, "phi") || strings.HasPrefix(base, "civ") {
+		u.Namespace = "latinLit"
+		prefix = "phi"
+		if strings.HasPrefix(base, "lat") {
+			base = "phi" + base[3:]
+		}
+	}
+	u.TextGroup = base
+
+	if n, err := strconv.Atoi(workID); err == nil {
+		u.Work = fmt.Sprintf("%s%03d", prefix, n)
+	} else {
+		u.Work = prefix + workID
+	}
+	return u
+}
+
+// URN returns the CTS URN of a line read by the parser.
+func (p *Parser) URN(l *Line) *URN {
+	u := NewURN(p.File.Name(), l.WorkID)
+	u.From = l.Citation
+	return u
+}
+
+// ExtractURN returns the passage a URN refers to. The parser must be
+// reading the file named by u.FileName().
+func (p *Parser) ExtractURN(u *URN) ([]*Line, error) {
+	if u.From == "" {
+		return p.WorkLines(u.WorkID())
+	}
+	to := u.To
+	if to == "" {
+		to = u.From
+	}
+	return p.ExtractRange(u.WorkID(), u.From, to)
+}
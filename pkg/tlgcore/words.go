@@ -0,0 +1,93 @@
+package tlgcore
+
+import (
+	"strings"
+	"unicode"
+)
+
+const betaDiacritics = ")(/\\=+|"
+
+// Words splits a line of raw Beta Code into word forms. Markup codes
+// are removed, capitals lose their '*' (diacritics are moved behind the
+// letter, so "*)AXILLEU/S" becomes "a)xilleu/s") and the result is
+// lower case. Elision marks stay attached to the word.
+func Words(beta string) []string {
+	var words []string
+	var cur strings.Builder
+
+	flush := func() {
+		if cur.Len() > 0 {
+			words = append(words, cur.String())
+			cur.Reset()
+		}
+	}
+
+	runes := []rune(beta)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '$' || r == '&' || r == '<' || r == '>' || r == '[' || r == ']' || r == '`' || r == '?':
+			// Font changes, formatting and brackets may occur
+			// inside a word; drop them and their number.
+			i = skipDigits(runes, i)
+		case r == '%' || r == '#' || r == '@' || r == '{' || r == '}' || r == '"':
+			flush()
+			i = skipDigits(runes, i)
+		case r == '*':
+			j := i + 1
+			for j < len(runes) && strings.ContainsRune(betaDiacritics, runes[j]) {
+				j++
+			}
+			if j < len(runes) && unicode.IsLetter(runes[j]) {
+				cur.WriteRune(unicode.ToLower(runes[j]))
+				cur.WriteString(string(runes[i+1 : j]))
+				i = j
+			}
+		case unicode.IsLetter(r) || strings.ContainsRune(betaDiacritics, r) || r == '\'':
+			cur.WriteRune(unicode.ToLower(r))
+		case unicode.IsDigit(r):
+			// Letter variants such as S1, S2 (sigma forms).
+			if cur.Len() == 0 {
+				flush()
+			}
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words
+}
+
+func skipDigits(runes []rune, i int) int {
+	for i+1 < len(runes) && unicode.IsDigit(runes[i+1]) {
+		i++
+	}
+	return i
+}
+
+// WordKey normalizes a word form for comparison. Grave accents count
+// as acute; strict keys also drop all accents, breathings and other
+// diacritics (see NormalizeStrict).
+func WordKey(w string, strict bool) string {
+	w = strings.ToLower(w)
+	if strict {
+		return NormalizeStrict(w)
+	}
+	return NormalizeBetaCode(w)
+}
+
+// QueryKey turns a search word in Unicode or Beta Code into a WordKey.
+func QueryKey(word string, strict bool) string {
+	for _, r := range word {
+		if r > 127 {
+			// ToBetaCode writes final sigma as j; the texts have s.
+			word = strings.ReplaceAll(ToBetaCode(word), "j", "s")
+			break
+		}
+	}
+	words := Words(word)
+	if len(words) == 0 {
+		return ""
+	}
+	return WordKey(words[0], strict)
+}
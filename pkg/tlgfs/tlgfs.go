@@ -0,0 +1,328 @@
+// Package tlgfs presents the corpus and the dictionaries as a 9P file
+// tree, so that acme, the plumber and shell tools can read texts with
+// plain file I/O:
+//
+//	/tlg/authors          authors of the TLG directory
+//	/tlg/0012/works       works of an author, as tlgviewer -list prints them
+//	/tlg/0012/1/text      text of a work, as tlgviewer prints it
+//	/tlg/0012/1/ctl       work metadata; write "range 1.1 1.50" to narrow text
+//	/tlg/0012/1/1.1-1.50  a passage (walkable, not listed)
+//	/phi/...              the same for the PHI (lat*.txt) directory
+//	/dict/lsj/<word>      LSJ entries of a Greek word
+//	/dict/ls/<word>       Lewis & Short entries of a Latin word
+//	/morph/<word>         analyses of a Greek word
+//	/lmorph/<word>        analyses of a Latin word
+//
+// Ranges set through ctl are kept per 9P connection.
+package tlgfs
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"tlgread/pkg/corpus"
+	"tlgread/pkg/lexicon"
+	"tlgread/pkg/ninep"
+	"tlgread/pkg/tlgcore"
+)
+
+type Config struct {
+	Corpus       []string // corpus directories, e.g. TLG-E and PHI-5
+	Greek, Latin *lexicon.Dictionary
+}
+
+// collection is /tlg or /phi: author IDs ("0012") mapped to files.
+type collection struct {
+	name    string
+	files   map[string]string
+	ids     []string
+	authors map[string]*corpus.Authors // by author ID, as files
+}
+
+// New builds the file tree.
+func New(cfg Config) (ninep.Directory, error) {
+	colls := map[string]*collection{
+		"tlg": {name: "tlg", files: make(map[string]string), authors: make(map[string]*corpus.Authors)},
+		"phi": {name: "phi", files: make(map[string]string), authors: make(map[string]*corpus.Authors)},
+	}
+	for _, dir := range cfg.Corpus {
+		files, err := corpus.Files(dir)
+		if err != nil {
+			return nil, err
+		}
+		names := corpus.NewAuthors(dir)
+		for _, f := range files {
+			base := strings.TrimSuffix(filepath.Base(f), filepath.Ext(f))
+			c := colls["tlg"]
+			if tlgcore.IsLatinName(base) {
+				c = colls["phi"]
+			}
+			id := base[3:]
+			if _, dup := c.files[id]; dup {
+				continue
+			}
+			c.files[id] = f
+			c.ids = append(c.ids, id)
+			c.authors[id] = names
+		}
+	}
+
+	root := &ninep.StaticDir{DirName: "/"}
+	for _, name := range []string{"tlg", "phi"} {
+		c := colls[name]
+		if len(c.ids) == 0 {
+			continue
+		}
+		sort.Strings(c.ids)
+		root.Children = append(root.Children, c.dir())
+	}
+	root.Children = append(root.Children,
+		&ninep.StaticDir{DirName: "dict", Children: []ninep.Node{
+			lookupDir("lsj", cfg.Greek, define),
+			lookupDir("ls", cfg.Latin, define),
+		}},
+		lookupDir("morph", cfg.Greek, analyze),
+		lookupDir("lmorph", cfg.Latin, analyze),
+	)
+	return root, nil
+}
+
+func (c *collection) authorName(id string) string {
+	return c.authors[id].Name(c.files[id])
+}
+
+func (c *collection) dir() ninep.Directory {
+	authors := &ninep.FileFunc{FileName: "authors", ReadFn: func(*ninep.Session) ([]byte, error) {
+		var b bytes.Buffer
+		for _, id := range c.ids {
+			fmt.Fprintf(&b, "%-8s | %s\n", id, c.authorName(id))
+		}
+		return b.Bytes(), nil
+	}}
+
+	return &ninep.DirFunc{
+		DirName: c.name,
+		WalkFn: func(s *ninep.Session, name string) (ninep.Node, error) {
+			if name == "authors" {
+				return authors, nil
+			}
+			if _, ok := c.files[name]; !ok {
+				return nil, ninep.ErrNotFound
+			}
+			return c.authorDir(name), nil
+		},
+		ListFn: func(*ninep.Session) ([]ninep.Node, error) {
+			nodes := []ninep.Node{authors}
+			for _, id := range c.ids {
+				nodes = append(nodes, c.authorDir(id))
+			}
+			return nodes, nil
+		},
+	}
+}
+
+// works returns the IDT metadata of an author sorted by work ID.
+func (c *collection) works(id string) ([]*tlgcore.WorkMetadata, error) {
+	p, err := tlgcore.OpenText(c.files[id])
+	if err != nil {
+		return nil, err
+	}
+	defer p.Close()
+
+	var works []*tlgcore.WorkMetadata
+	for _, meta := range p.IDTData {
+		works = append(works, meta)
+	}
+	sort.Slice(works, func(i, j int) bool {
+		a, _ := strconv.Atoi(works[i].ID)
+		b, _ := strconv.Atoi(works[j].ID)
+		return a < b
+	})
+	return works, nil
+}
+
+func (c *collection) authorDir(id string) ninep.Directory {
+	list := &ninep.FileFunc{FileName: "works", ReadFn: func(*ninep.Session) ([]byte, error) {
+		works, err := c.works(id)
+		if err != nil {
+			return nil, err
+		}
+		var b bytes.Buffer
+		for _, meta := range works {
+			fmt.Fprintf(&b, "ID:%-4s | %s\n", meta.ID, meta.Title)
+		}
+		return b.Bytes(), nil
+	}}
+
+	return &ninep.DirFunc{
+		DirName: id,
+		WalkFn: func(s *ninep.Session, name string) (ninep.Node, error) {
+			if name == "works" {
+				return list, nil
+			}
+			works, err := c.works(id)
+			if err != nil {
+				return nil, err
+			}
+			for _, meta := range works {
+				if meta.ID == tlgcore.NormalizeID(name) {
+					return c.workDir(id, meta), nil
+				}
+			}
+			return nil, ninep.ErrNotFound
+		},
+		ListFn: func(*ninep.Session) ([]ninep.Node, error) {
+			works, err := c.works(id)
+			if err != nil {
+				return nil, err
+			}
+			nodes := []ninep.Node{list}
+			for _, meta := range works {
+				nodes = append(nodes, c.workDir(id, meta))
+			}
+			return nodes, nil
+		},
+	}
+}
+
+func (c *collection) workDir(id string, meta *tlgcore.WorkMetadata) ninep.Directory {
+	key := c.name + "/" + id + "/" + meta.ID
+
+	text := &ninep.FileFunc{FileName: "text", ReadFn: func(s *ninep.Session) ([]byte, error) {
+		from, to, _ := strings.Cut(s.Get(key), " ")
+		return c.passage(id, meta.ID, from, to)
+	}}
+
+	ctl := &ninep.FileFunc{
+		FileName: "ctl",
+		ReadFn: func(s *ninep.Session) ([]byte, error) {
+			var b bytes.Buffer
+			fmt.Fprintf(&b, "author %s\n", c.authorName(id))
+			fmt.Fprintf(&b, "title %s\n", meta.Title)
+			fmt.Fprintf(&b, "urn %s\n", tlgcore.NewURN(filepath.Base(c.files[id]), meta.ID))
+			var labels []string
+			for _, cit := range meta.Citations {
+				labels = append(labels, strconv.Quote(cit.Label))
+			}
+			fmt.Fprintf(&b, "citation %s\n", strings.Join(labels, " "))
+			if r := s.Get(key); r != "" {
+				fmt.Fprintf(&b, "range %s\n", r)
+			}
+			return b.Bytes(), nil
+		},
+		WriteFn: func(s *ninep.Session, data []byte) error {
+			for _, line := range strings.Split(string(data), "\n") {
+				f := strings.Fields(line)
+				switch {
+				case len(f) == 0:
+				case f[0] == "range" && len(f) == 1:
+					s.Set(key, "")
+				case f[0] == "range" && len(f) <= 3:
+					to := f[len(f)-1]
+					s.Set(key, f[1]+" "+to)
+				default:
+					return fmt.Errorf("bad ctl message %q", line)
+				}
+			}
+			return nil
+		},
+	}
+
+	return &ninep.DirFunc{
+		DirName: meta.ID,
+		WalkFn: func(s *ninep.Session, name string) (ninep.Node, error) {
+			switch name {
+			case "text":
+				return text, nil
+			case "ctl":
+				return ctl, nil
+			}
+			from, to, ok := strings.Cut(name, "-")
+			if !ok {
+				to = from
+			}
+			data, err := c.passage(id, meta.ID, from, to)
+			if err != nil || len(data) == 0 {
+				return nil, ninep.ErrNotFound
+			}
+			return &ninep.FileFunc{FileName: name, ReadFn: func(*ninep.Session) ([]byte, error) {
+				return data, nil
+			}}, nil
+		},
+		ListFn: func(*ninep.Session) ([]ninep.Node, error) {
+			return []ninep.Node{text, ctl}, nil
+		},
+	}
+}
+
+// passage formats the lines of a work the way tlgviewer prints them.
+func (c *collection) passage(id, workID, from, to string) ([]byte, error) {
+	p, err := tlgcore.OpenText(c.files[id])
+	if err != nil {
+		return nil, err
+	}
+	defer p.Close()
+
+	var lines []*tlgcore.Line
+	if from != "" || to != "" {
+		lines, err = p.ExtractRange(workID, from, to)
+	} else {
+		lines, err = p.WorkLines(workID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	for _, l := range lines {
+		if strings.TrimSpace(l.Text) != "" {
+			b.WriteString(tlgcore.FormatLine(l))
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// lookupDir is a directory whose files are the words looked up in it.
+// A word with no result does not exist.
+func lookupDir(name string, d *lexicon.Dictionary, fn func(*lexicon.Dictionary, string) []byte) ninep.Directory {
+	return &ninep.DirFunc{
+		DirName: name,
+		WalkFn: func(s *ninep.Session, word string) (ninep.Node, error) {
+			if d == nil {
+				return nil, ninep.ErrNotFound
+			}
+			data := fn(d, word)
+			if len(data) == 0 {
+				return nil, ninep.ErrNotFound
+			}
+			return &ninep.FileFunc{FileName: word, ReadFn: func(*ninep.Session) ([]byte, error) {
+				return data, nil
+			}}, nil
+		},
+	}
+}
+
+func analyze(d *lexicon.Dictionary, word string) []byte {
+	results, err := d.Analyze(word)
+	if err != nil {
+		return nil
+	}
+	var b bytes.Buffer
+	for _, r := range results {
+		fmt.Fprintln(&b, d.FormatAnalysis(word, r))
+	}
+	return b.Bytes()
+}
+
+func define(d *lexicon.Dictionary, word string) []byte {
+	entries, _ := d.Define(word)
+	var b bytes.Buffer
+	for _, e := range entries {
+		fmt.Fprintf(&b, "[ENTRY: %s]\n%s\n\n", e.Headword, e.Text())
+	}
+	return b.Bytes()
+}
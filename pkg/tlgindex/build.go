@@ -0,0 +1,330 @@
+// Package tlgindex maintains an on-disk inverted index of a TLG/PHI
+// corpus: normalized word forms mapped to (file, work, citation)
+// postings.
+//
+// An index directory holds
+//
+//	files.lst       name, size and modification time of each indexed file
+//	shards/*.pst    sorted postings of a single corpus file
+//	postings.txt    all shards merged, sorted by key
+//	postings.idt    sparse offsets into postings.txt ('key' => offset)
+//
+// A posting line is "strict<TAB>word<TAB>file<TAB>work<TAB>citation",
+// where strict is the word without diacritics (tlgcore.WordKey).
+package tlgindex
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"tlgread/pkg/corpus"
+	"tlgread/pkg/tlgcore"
+)
+
+const (
+	manifestName = "files.lst"
+	shardDir     = "shards"
+	postingsName = "postings.txt"
+	offsetsName  = "postings.idt"
+
+	sparseEvery = 1024 // postings between two entries of postings.idt
+	mergeFan    = 256  // shards merged at once (bounded by open files)
+)
+
+type fileStamp struct {
+	Size    int64
+	ModTime int64
+}
+
+// Build indexes every corpus file of corpusDir into indexDir. Only
+// files that changed since the last build are parsed again; removed
+// files are dropped. logf, if not nil, receives progress messages.
+func Build(corpusDir, indexDir string, workers int, logf func(format string, args ...any)) error {
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+
+	files, err := corpus.Files(corpusDir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(indexDir, shardDir), 0755); err != nil {
+		return err
+	}
+
+	old := readManifest(filepath.Join(indexDir, manifestName))
+	current := make(map[string]fileStamp, len(files))
+	var stale []string
+
+	for _, path := range files {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		name := filepath.Base(path)
+		st := fileStamp{Size: fi.Size(), ModTime: fi.ModTime().UnixNano()}
+		current[name] = st
+
+		prev, ok := old[name]
+		if _, err := os.Stat(shardPath(indexDir, name)); !ok || prev != st || err != nil {
+			stale = append(stale, path)
+		}
+	}
+
+	removed := 0
+	for name := range old {
+		if _, ok := current[name]; !ok {
+			os.Remove(shardPath(indexDir, name))
+			removed++
+		}
+	}
+
+	_, err = os.Stat(filepath.Join(indexDir, postingsName))
+	if len(stale) == 0 && removed == 0 && err == nil {
+		logf("Index is up to date (%d files).\n", len(files))
+		return nil
+	}
+
+	logf("Indexing %d of %d files...\n", len(stale), len(files))
+	var mu sync.Mutex
+	done := 0
+	err = corpus.Scan(stale, workers, func(path string) error {
+		if err := writeShard(path, shardPath(indexDir, filepath.Base(path))); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		mu.Lock()
+		done++
+		if done%100 == 0 {
+			logf("  %d/%d\n", done, len(stale))
+		}
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logf("Merging postings...\n")
+	var shards []string
+	for _, path := range files {
+		shards = append(shards, shardPath(indexDir, filepath.Base(path)))
+	}
+	if err := mergeAll(shards, indexDir); err != nil {
+		return err
+	}
+	if err := writeOffsets(indexDir); err != nil {
+		return err
+	}
+	return writeManifest(filepath.Join(indexDir, manifestName), current)
+}
+
+func shardPath(indexDir, name string) string {
+	return filepath.Join(indexDir, shardDir, strings.TrimSuffix(name, filepath.Ext(name))+".pst")
+}
+
+func readManifest(path string) map[string]fileStamp {
+	m := make(map[string]fileStamp)
+	f, err := os.Open(path)
+	if err != nil {
+		return m
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		size, _ := strconv.ParseInt(fields[1], 10, 64)
+		mod, _ := strconv.ParseInt(fields[2], 10, 64)
+		m[fields[0]] = fileStamp{Size: size, ModTime: mod}
+	}
+	return m
+}
+
+func writeManifest(path string, m map[string]fileStamp) error {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&sb, "%s %d %d\n", name, m[name].Size, m[name].ModTime)
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0644)
+}
+
+// writeShard parses one corpus file and writes its sorted postings.
+func writeShard(path, out string) error {
+	p, err := tlgcore.OpenText(path)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	file := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	var postings []string
+	for l, err := range p.Lines() {
+		if err != nil {
+			return err
+		}
+		for _, w := range tlgcore.Words(l.Beta) {
+			word := tlgcore.WordKey(w, false)
+			strict := tlgcore.WordKey(w, true)
+			if strict == "" {
+				continue
+			}
+			postings = append(postings, strings.Join([]string{strict, word, file, l.WorkID, l.Citation}, "\t"))
+		}
+	}
+	sort.Strings(postings)
+
+	tmp := out + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, line := range postings {
+		w.WriteString(line)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, out)
+}
+
+// mergeAll merges the shards into postings.txt, at most mergeFan files
+// at a time.
+func mergeAll(shards []string, indexDir string) error {
+	var temps []string
+	defer func() {
+		for _, t := range temps {
+			os.Remove(t)
+		}
+	}()
+
+	for round := 0; len(shards) > mergeFan; round++ {
+		var next []string
+		for i := 0; i < len(shards); i += mergeFan {
+			end := min(i+mergeFan, len(shards))
+			t := filepath.Join(indexDir, fmt.Sprintf("merge.%d.%d.tmp", round, i))
+			if err := mergeFiles(shards[i:end], t); err != nil {
+				return err
+			}
+			temps = append(temps, t)
+			next = append(next, t)
+		}
+		shards = next
+	}
+
+	out := filepath.Join(indexDir, postingsName)
+	if err := mergeFiles(shards, out+".tmp"); err != nil {
+		return err
+	}
+	return os.Rename(out+".tmp", out)
+}
+
+type mergeItem struct {
+	line    string
+	scanner *bufio.Scanner
+}
+
+type mergeHeap []*mergeItem
+
+func (h mergeHeap) Len() int           { return len(h) }
+func (h mergeHeap) Less(i, j int) bool { return h[i].line < h[j].line }
+func (h mergeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x any)        { *h = append(*h, x.(*mergeItem)) }
+func (h *mergeHeap) Pop() any {
+	old := *h
+	it := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return it
+}
+
+func mergeFiles(inputs []string, out string) error {
+	h := &mergeHeap{}
+	for _, in := range inputs {
+		f, err := os.Open(in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		s := bufio.NewScanner(f)
+		if s.Scan() {
+			heap.Push(h, &mergeItem{line: s.Text(), scanner: s})
+		}
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for h.Len() > 0 {
+		it := (*h)[0]
+		w.WriteString(it.line)
+		w.WriteByte('\n')
+		if it.scanner.Scan() {
+			it.line = it.scanner.Text()
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeOffsets writes the sparse key index of postings.txt.
+func writeOffsets(indexDir string) error {
+	in, err := os.Open(filepath.Join(indexDir, postingsName))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(filepath.Join(indexDir, offsetsName))
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(out)
+
+	reader := bufio.NewReader(in)
+	var offset int64
+	for n := 0; ; n++ {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		if n%sparseEvery == 0 {
+			key, _, _ := strings.Cut(line, "\t")
+			fmt.Fprintf(w, "'%s' => %d\n", key, offset)
+		}
+		offset += int64(len(line))
+	}
+	if err := w.Flush(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
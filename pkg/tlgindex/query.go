@@ -0,0 +1,174 @@
+package tlgindex
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"tlgread/pkg/corpus"
+	"tlgread/pkg/tlgcore"
+)
+
+// Posting is one occurrence of a word form in the corpus.
+type Posting struct {
+	Word     string // word form (tlgcore.WordKey, not strict)
+	File     string // corpus file without extension, e.g. "tlg0012"
+	Work     string
+	Citation string
+}
+
+type Index struct {
+	dir     string
+	keys    []string
+	offsets []int64
+}
+
+// Open loads the sparse key index of an index directory.
+func Open(dir string) (*Index, error) {
+	f, err := os.Open(filepath.Join(dir, offsetsName))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ix := &Index{dir: dir}
+	re := regexp.MustCompile(`^'(.*)' => (\d+)$`)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		m := re.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		offset, _ := strconv.ParseInt(m[2], 10, 64)
+		ix.keys = append(ix.keys, m[1])
+		ix.offsets = append(ix.offsets, offset)
+	}
+	return ix, scanner.Err()
+}
+
+// Lookup returns the postings of a word key (see tlgcore.QueryKey).
+// Strict lookups match every form that differs only in diacritics.
+func (ix *Index) Lookup(key string, strict bool) ([]Posting, error) {
+	strictKey := key
+	if !strict {
+		strictKey = tlgcore.WordKey(key, true)
+	}
+
+	// Start at the last sparse entry before the key: earlier lines of
+	// the same key may precede an entry that equals it.
+	i := sort.SearchStrings(ix.keys, strictKey)
+	var offset int64
+	if i > 0 {
+		offset = ix.offsets[i-1]
+	}
+
+	f, err := os.Open(filepath.Join(ix.dir, postingsName))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, 0); err != nil {
+		return nil, err
+	}
+
+	var postings []Posting
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 5 {
+			continue
+		}
+		if fields[0] < strictKey {
+			continue
+		}
+		if fields[0] > strictKey {
+			break
+		}
+		if !strict && fields[1] != key {
+			continue
+		}
+		postings = append(postings, Posting{Word: fields[1], File: fields[2], Work: fields[3], Citation: fields[4]})
+	}
+	return postings, scanner.Err()
+}
+
+// Hits reads the lines the postings point to from the corpus files in
+// corpusDir. Each work is read once, starting at its IDT block. Several
+// lines may share a citation (unnumbered lines, or the lines of one
+// section); every one of them that has a posted word is a hit.
+func Hits(corpusDir string, postings []Posting) ([]corpus.Hit, error) {
+	type workKey struct{ file, work string }
+	want := make(map[workKey]map[string][]string) // citation -> words
+	var order []workKey
+	for _, p := range postings {
+		k := workKey{p.File, p.Work}
+		if want[k] == nil {
+			want[k] = make(map[string][]string)
+			order = append(order, k)
+		}
+		if !slices.Contains(want[k][p.Citation], p.Word) {
+			want[k][p.Citation] = append(want[k][p.Citation], p.Word)
+		}
+	}
+	sort.Slice(order, func(i, j int) bool {
+		if order[i].file != order[j].file {
+			return order[i].file < order[j].file
+		}
+		return tlgcore.CompareCitation(order[i].work, order[j].work) < 0
+	})
+
+	var hits []corpus.Hit
+	parsers := make(map[string]*tlgcore.Parser)
+	defer func() {
+		for _, p := range parsers {
+			p.Close()
+		}
+	}()
+
+	for _, k := range order {
+		path := filepath.Join(corpusDir, k.file+".txt")
+		p, ok := parsers[path]
+		if !ok {
+			var err error
+			p, err = tlgcore.OpenText(path)
+			if err != nil {
+				return hits, err
+			}
+			parsers[path] = p
+		}
+
+		lines, err := p.WorkLines(k.work)
+		if err != nil {
+			return hits, err
+		}
+		title := ""
+		if meta := p.IDTData[k.work]; meta != nil {
+			title = meta.Title
+		}
+		for _, l := range lines {
+			words, ok := want[k][l.Citation]
+			if !ok {
+				continue
+			}
+			if word := lineWord(l, words); word != "" {
+				hits = append(hits, corpus.Hit{Path: path, Title: title, URN: p.URN(l), Line: l, Word: word})
+			}
+		}
+	}
+	return hits, nil
+}
+
+// lineWord returns the first word of the line that is one of words.
+func lineWord(l *tlgcore.Line, words []string) string {
+	for _, w := range tlgcore.Words(l.Beta) {
+		if key := tlgcore.WordKey(w, false); slices.Contains(words, key) {
+			return key
+		}
+	}
+	return ""
+}
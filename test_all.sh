@@ -20,6 +20,7 @@ go build -o search ./cmd/search
 # Build the new test suite
 echo "   - Building test_full..."
 go build -o test_full ./cmd/test_full
+go build -o test_betacode ./cmd/test_betacode
 
 echo "Build Success!"
 echo "---------------------------------------------------"
@@ -33,7 +34,10 @@ else
     echo "Defaulting to current directory..."
 fi
 
-echo "2. Running Feature Test Suite on: $DIR"
+echo "2. Running Beta Code Conformance Test"
+./test_betacode
+
+echo "3. Running Feature Test Suite on: $DIR"
 ./test_full -d "$DIR"
 
-rm tlgviewer readauth search test_full
+rm tlgviewer readauth search test_full test_betacode